@@ -6,8 +6,10 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/SANEKNAYMCHIK/newsBot/internal/config"
 	"github.com/SANEKNAYMCHIK/newsBot/internal/database"
@@ -36,27 +38,103 @@ func main() {
 	sourceRepo := repositories.NewSourceRepository(db.Pool)
 	categoryRepo := repositories.NewCategoryRepository(db.Pool)
 	subscriptionRepo := repositories.NewSubscriptionRepository(db.Pool)
+	deliveryRepo := repositories.NewDeliveryRepository(db.Pool)
+	settingsRepo := repositories.NewSettingsRepository(db.Pool)
+	retentionRepo := repositories.NewRetentionRepository(db.Pool)
 
-	rssParser := services.NewRssParser(10)
-	rssService := services.NewRssService(sourceRepo, newsRepo, rssParser)
+	networkGuard, err := services.NewNetworkGuard(cfg.FetchAllowedNetworks)
+	if err != nil {
+		log.Fatalf("Invalid fetch allowed networks: %v", err)
+	}
+	rssParser := services.NewRssParser(10, networkGuard, services.FetchLimits{
+		ConnectTimeout: time.Duration(cfg.FetchConnectTimeout) * time.Second,
+		ReadTimeout:    time.Duration(cfg.FetchReadTimeout) * time.Second,
+		MaxBodyBytes:   int64(cfg.FetchMaxBodyBytes),
+		MaxItems:       cfg.FetchMaxItems,
+		MaxPerHost:     cfg.FetchMaxPerHost,
+	}, services.RetryPolicy{
+		MaxAttempts:   cfg.FetchMaxAttempts,
+		BaseDelay:     time.Duration(cfg.FetchRetryBaseDelay) * time.Second,
+		MaxDelay:      time.Duration(cfg.FetchRetryMaxDelay) * time.Second,
+		MaxRetryAfter: time.Duration(cfg.FetchRetryAfterMax) * time.Second,
+	})
+	rssService := services.NewRssService(
+		sourceRepo,
+		newsRepo,
+		deliveryRepo,
+		rssParser,
+		services.NewArticleExtractor(networkGuard),
+		cfg.SourceMaxFailures,
+		time.Duration(cfg.ParserInterval)*time.Minute,
+		cfg.NotifyNewsEdits,
+	)
 
 	refreshService := services.NewRefreshService(
 		rssService,
-		subscriptionRepo,
+		repositories.NewRefreshRepository(db.Pool),
 		5,
 		100,
 		3*time.Minute,
 	)
-	go refreshService.Start(context.Background())
-	newsWorker := worker.NewNewsWorker(rssService, time.Duration(cfg.ParserInterval)*time.Minute)
+	refreshCtx, stopRefresh := context.WithCancel(context.Background())
+	go refreshService.Start(refreshCtx)
+
+	retentionService := services.NewRetentionService(
+		retentionRepo,
+		cfg.RetentionDays,
+		cfg.RetentionMode,
+		time.Duration(cfg.RetentionInterval)*time.Hour,
+		cfg.RetentionBatchSize,
+		cfg.RetentionKeyGraceDays,
+	)
+
+	partitionService := services.NewPartitionService(newsRepo, cfg.NewsPartitionsAhead, 24*time.Hour)
+	newsWorker := worker.NewNewsWorker(rssService, time.Duration(cfg.FetchTickInterval)*time.Second)
+
+	// Плановый сбор новостей, очистку и создание секций выполняет только
+	// процесс-лидер, остальные реплики ждут и подхватывают работу при его падении
+	leaderElector := database.NewLeaderElector(
+		db.Pool,
+		"newsbot-scheduler",
+		time.Duration(cfg.LeaderHeartbeatInterval)*time.Second,
+	)
+	urlBackfillService := services.NewURLBackfillService(repositories.NewBackfillRepository(db.Pool), 1000)
 
+	// Блокировка снимается только после того, как все задачи лидера
+	// завершились, иначе новый лидер выполнял бы их одновременно со старым
+	leaderDone := make(chan struct{})
 	go func() {
-		log.Println("Starting RSS news worker...")
-		newsWorker.Start(context.Background())
+		defer close(leaderDone)
+		leaderElector.Run(refreshCtx, func(ctx context.Context) {
+			var jobs sync.WaitGroup
+			jobs.Add(3)
+			go func() {
+				defer jobs.Done()
+				if err := urlBackfillService.Run(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("Failed to backfill canonical URLs: %v", err)
+				}
+			}()
+			go func() {
+				defer jobs.Done()
+				retentionService.Start(ctx)
+			}()
+			go func() {
+				defer jobs.Done()
+				partitionService.Start(ctx)
+			}()
+
+			log.Println("Starting RSS news worker...")
+			newsWorker.Start(ctx)
+
+			<-ctx.Done()
+			newsWorker.Stop()
+			jobs.Wait()
+		})
 	}()
 
 	defer func() {
-		newsWorker.Stop()
+		stopRefresh()
+		<-leaderDone
 		log.Println("Workers stopped")
 	}()
 
@@ -64,9 +142,10 @@ func main() {
 	userService := services.NewUserService(userRepo)
 	newsService := services.NewNewsService(newsRepo, sourceRepo, subscriptionRepo)
 	subscriptionService := services.NewSubscriptionService(subscriptionRepo, sourceRepo)
-	sourceService := services.NewSourceService(sourceRepo)
+	sourceService := services.NewSourceService(sourceRepo, services.NewFeedDiscoverer(networkGuard, rssParser), rssParser)
 	categoryService := services.NewCategoryService(categoryRepo)
 	adminService := services.NewAdminService(userRepo)
+	settingsService := services.NewSettingsService(settingsRepo)
 
 	router := handlers.NewRouter(
 		authService,
@@ -77,6 +156,8 @@ func main() {
 		sourceService,
 		adminService,
 		refreshService,
+		settingsService,
+		retentionService,
 		jwtManager,
 		cfg,
 	)
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/SANEKNAYMCHIK/newsBot/internal/bot"
 	"github.com/SANEKNAYMCHIK/newsBot/internal/config"
@@ -35,24 +36,53 @@ func main() {
 	newsRepo := repositories.NewNewsRepository(db.Pool)
 	subscriptionRepo := repositories.NewSubscriptionRepository(db.Pool)
 	categoryRepo := repositories.NewCategoryRepository(db.Pool)
+	deliveryRepo := repositories.NewDeliveryRepository(db.Pool)
+	settingsRepo := repositories.NewSettingsRepository(db.Pool)
 
 	jwtManager := auth.NewJWTManager(cfg.JWTSecret)
 	authService := services.NewAuthService(userRepo, jwtManager)
 	adminService := services.NewAdminService(userRepo)
 	categoryService := services.NewCategoryService(categoryRepo)
-	sourceService := services.NewSourceService(sourceRepo)
 	newsService := services.NewNewsService(newsRepo, sourceRepo, subscriptionRepo)
+	settingsService := services.NewSettingsService(settingsRepo)
 
-	rssParser := services.NewRssParser(10)
-	rssService := services.NewRssService(sourceRepo, newsRepo, rssParser)
+	networkGuard, err := services.NewNetworkGuard(cfg.FetchAllowedNetworks)
+	if err != nil {
+		log.Fatalf("Неверный список разрешенных сетей: %v", err)
+	}
+	rssParser := services.NewRssParser(10, networkGuard, services.FetchLimits{
+		ConnectTimeout: time.Duration(cfg.FetchConnectTimeout) * time.Second,
+		ReadTimeout:    time.Duration(cfg.FetchReadTimeout) * time.Second,
+		MaxBodyBytes:   int64(cfg.FetchMaxBodyBytes),
+		MaxItems:       cfg.FetchMaxItems,
+		MaxPerHost:     cfg.FetchMaxPerHost,
+	}, services.RetryPolicy{
+		MaxAttempts:   cfg.FetchMaxAttempts,
+		BaseDelay:     time.Duration(cfg.FetchRetryBaseDelay) * time.Second,
+		MaxDelay:      time.Duration(cfg.FetchRetryMaxDelay) * time.Second,
+		MaxRetryAfter: time.Duration(cfg.FetchRetryAfterMax) * time.Second,
+	})
+	sourceService := services.NewSourceService(sourceRepo, services.NewFeedDiscoverer(networkGuard, rssParser), rssParser)
+	rssService := services.NewRssService(
+		sourceRepo,
+		newsRepo,
+		deliveryRepo,
+		rssParser,
+		services.NewArticleExtractor(networkGuard),
+		cfg.SourceMaxFailures,
+		time.Duration(cfg.ParserInterval)*time.Minute,
+		cfg.NotifyNewsEdits,
+	)
 	refreshService := services.NewRefreshService(
 		rssService,
-		subscriptionRepo,
+		repositories.NewRefreshRepository(db.Pool),
 		5,
 		100,
 		3*time.Minute,
 	)
-	go refreshService.Start(context.Background())
+	refreshCtx, stopRefresh := context.WithCancel(context.Background())
+	defer stopRefresh()
+	go refreshService.Start(refreshCtx)
 
 	botService := bot.NewBotService(
 		authService,
@@ -66,6 +96,7 @@ func main() {
 		categoryService,
 		sourceService,
 		refreshService,
+		settingsService,
 	)
 
 	telegramBot, err := tgbotapi.NewBotAPI(cfg.TelegramBotToken)
@@ -87,6 +118,7 @@ func main() {
 		{Command: "categories", Description: "Показать категории"},
 		{Command: "update", Description: "Обновить новости вручную"},
 		{Command: "update_status", Description: "Статус обновления новостей"},
+		{Command: "settings", Description: "Настройки доставки новостей"},
 	}
 
 	adminCommands := []tgbotapi.BotCommand{
@@ -107,6 +139,51 @@ func main() {
 
 	handler := bot.NewHandler(telegramBot, botService)
 
+	notifier := bot.NewNotifier(telegramBot)
+	deliveryCtx, stopDelivery := context.WithCancel(context.Background())
+	defer stopDelivery()
+	deliveryService := services.NewDeliveryService(
+		deliveryRepo,
+		notifier,
+		time.Duration(cfg.DeliveryInterval)*time.Second,
+		50,
+		cfg.DeliveryMaxAttempts,
+	)
+
+	digestService := services.NewDigestService(
+		settingsRepo,
+		deliveryRepo,
+		notifier,
+		time.Duration(cfg.DigestInterval)*time.Second,
+		100,
+		cfg.DeliveryMaxAttempts,
+	)
+
+	// Рассылку и дайджесты выполняет только одна реплика бота, иначе дайджест
+	// мог бы собираться и отправляться двумя процессами одновременно
+	deliveryElector := database.NewLeaderElector(
+		db.Pool,
+		"newsbot-delivery",
+		time.Duration(cfg.LeaderHeartbeatInterval)*time.Second,
+	)
+	deliveryDone := make(chan struct{})
+	go func() {
+		defer close(deliveryDone)
+		deliveryElector.Run(deliveryCtx, func(ctx context.Context) {
+			var jobs sync.WaitGroup
+			jobs.Add(2)
+			go func() {
+				defer jobs.Done()
+				deliveryService.Start(ctx)
+			}()
+			go func() {
+				defer jobs.Done()
+				digestService.Start(ctx)
+			}()
+			jobs.Wait()
+		})
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
@@ -123,6 +200,9 @@ func main() {
 		select {
 		case <-quit:
 			log.Println("Остановка бота...")
+			stopDelivery()
+			stopRefresh()
+			<-deliveryDone
 			telegramBot.StopReceivingUpdates()
 			wg.Wait()
 			log.Println("Бот остановлен")
@@ -239,5 +239,42 @@ func (h *Handler) handleAdminStatsCommand(ctx context.Context, message *tgbotapi
 	text += fmt.Sprintf("Источников: *%d*\n", stats["sources_count"])
 	text += fmt.Sprintf("Новостей: *%d*\n", stats["news_count"])
 
+	failing, err := h.service.GetFailingSources(ctx, 5)
+	if err != nil {
+		text += "\nНе удалось получить состояние источников"
+		h.sendMessage(message.Chat.ID, text)
+		return
+	}
+
+	text += fmt.Sprintf("\n*Проблемные источники:* %d\n", failing.Total)
+	for _, source := range failing.Data {
+		status := "активен"
+		if !source.IsActive {
+			status = "отключен"
+		}
+		text += fmt.Sprintf("\n• `%d` %s (%s)\n", source.SourceID, tgbotapi.EscapeText(tgbotapi.ModeMarkdown, source.Name), status)
+		text += fmt.Sprintf("Ошибок подряд: %d", source.ConsecutiveFailures)
+		if source.LastHTTPStatus != nil {
+			text += fmt.Sprintf(", HTTP %d", *source.LastHTTPStatus)
+		}
+		text += "\n"
+		if source.LastSuccessAt != nil {
+			text += fmt.Sprintf("Последний успех: %s (UTC)\n", source.LastSuccessAt.UTC().Format("02.01.2006 15:04"))
+		} else {
+			text += "Последний успех: никогда\n"
+		}
+		if source.LastError != nil {
+			text += fmt.Sprintf("Ошибка: %s\n", tgbotapi.EscapeText(tgbotapi.ModeMarkdown, truncateText(*source.LastError, 150)))
+		}
+	}
+
 	h.sendMessage(message.Chat.ID, text)
 }
+
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "..."
+}
@@ -0,0 +1,252 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/SANEKNAYMCHIK/newsBot/internal/services"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Найденные ленты хранятся до выбора пользователя: в callback data
+// помещается только короткий токен, адреса лент туда не влезают.
+const feedChoiceTTL = 30 * time.Minute
+
+type feedChoice struct {
+	candidates []models.FeedCandidate
+	name       string
+	categoryID *int64
+	expiresAt  time.Time
+}
+
+type feedChoices struct {
+	mu    sync.Mutex
+	items map[string]feedChoice
+}
+
+func newFeedChoices() *feedChoices {
+	return &feedChoices{items: make(map[string]feedChoice)}
+}
+
+func (c *feedChoices) put(choice feedChoice) string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	token := hex.EncodeToString(buf)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for key, item := range c.items {
+		if now.After(item.expiresAt) {
+			delete(c.items, key)
+		}
+	}
+	choice.expiresAt = now.Add(feedChoiceTTL)
+	c.items[token] = choice
+	return token
+}
+
+func (c *feedChoices) get(token string) (feedChoice, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	choice, ok := c.items[token]
+	if !ok || time.Now().After(choice.expiresAt) {
+		return feedChoice{}, false
+	}
+	return choice, true
+}
+
+// FeedOption - найденная лента и уже существующий источник с ее адресом.
+type FeedOption struct {
+	models.FeedCandidate
+	SourceID int64
+}
+
+func (s *BotService) DiscoverFeeds(ctx context.Context, pageURL string) ([]models.FeedCandidate, error) {
+	return s.sourceService.DiscoverFeeds(ctx, pageURL)
+}
+
+// PrepareFeedChoice запоминает найденные ленты и возвращает токен выбора.
+func (s *BotService) PrepareFeedChoice(ctx context.Context, candidates []models.FeedCandidate, name string, categoryID *int64) (string, []FeedOption) {
+	options := make([]FeedOption, len(candidates))
+	for i, candidate := range candidates {
+		options[i].FeedCandidate = candidate
+		if existing, err := s.sourceService.FindByURL(ctx, candidate.URL); errors.Is(err, services.ErrSourceAlreadyExists) {
+			options[i].SourceID = existing.ID
+		}
+	}
+	token := s.feedChoices.put(feedChoice{candidates: candidates, name: name, categoryID: categoryID})
+	return token, options
+}
+
+// AddFeed добавляет выбранную ленту как источник (или находит уже добавленный)
+// и при subscribe подписывает на него пользователя. Превью возвращается только
+// для нового источника.
+func (s *BotService) AddFeed(ctx context.Context, token string, index int, userID int64, subscribe bool) (*models.Source, *models.FeedPreview, error) {
+	choice, ok := s.feedChoices.get(token)
+	if !ok || index < 0 || index >= len(choice.candidates) {
+		return nil, nil, fmt.Errorf("выбор устарел, отправьте ссылку еще раз")
+	}
+	candidate := choice.candidates[index]
+
+	source, err := s.sourceService.FindByURL(ctx, candidate.URL)
+	if err != nil && !errors.Is(err, services.ErrSourceAlreadyExists) {
+		return nil, nil, err
+	}
+	var preview *models.FeedPreview
+	if source == nil {
+		name := choice.name
+		if name == "" {
+			name = feedName(candidate)
+		}
+		created, err := s.sourceService.CreateSource(ctx, &models.CreateSourceRequest{
+			Name:       name,
+			URL:        candidate.URL,
+			CategoryID: choice.categoryID,
+			IsActive:   true,
+		})
+		if err != nil {
+			return nil, nil, describeSourceError(err)
+		}
+		source, preview = &created.Source, created.Preview
+	}
+
+	if subscribe {
+		if err := s.SubscribeUser(ctx, userID, int(source.ID)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return source, preview, nil
+}
+
+// describeSourceError переводит ошибки добавления источника для пользователя.
+func describeSourceError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrSourceAlreadyExists):
+		return fmt.Errorf("источник с таким URL уже существует")
+	case errors.Is(err, services.ErrFeedNotFound):
+		return fmt.Errorf("по этой ссылке не найдено RSS-ленты")
+	case errors.Is(err, services.ErrFeedTooLarge):
+		return fmt.Errorf("лента слишком большая (%v)", err)
+	case errors.Is(err, services.ErrInvalidFeed):
+		return fmt.Errorf("по ссылке не RSS/Atom-лента (%v)", err)
+	}
+	return err
+}
+
+// formatFeedPreview показывает заголовок ленты и ее последние новости.
+func formatFeedPreview(preview *models.FeedPreview) string {
+	if preview == nil {
+		return ""
+	}
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("\n\nЛента: *%s*, новостей в ленте: %d",
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdown, preview.Title), preview.ItemCount))
+	if len(preview.Items) > 0 {
+		text.WriteString("\nПоследние новости:")
+		for _, item := range preview.Items {
+			text.WriteString("\n• " + tgbotapi.EscapeText(tgbotapi.ModeMarkdown, truncateText(item.Title, 100)))
+		}
+	}
+	return text.String()
+}
+
+func feedName(candidate models.FeedCandidate) string {
+	if candidate.Title != "" {
+		return truncateText(candidate.Title, 100)
+	}
+	if u, err := url.Parse(candidate.URL); err == nil && u.Host != "" {
+		return strings.TrimPrefix(u.Hostname(), "www.")
+	}
+	return candidate.URL
+}
+
+// looksLikeURL проверяет, что сообщение - одна ссылка на сайт.
+func looksLikeURL(text string) bool {
+	text = strings.TrimSpace(text)
+	if strings.ContainsAny(text, " \n\t") {
+		return false
+	}
+	u, err := url.Parse(text)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func (h *Handler) handleFeedURL(ctx context.Context, message *tgbotapi.Message) {
+	pageURL := strings.TrimSpace(message.Text)
+	candidates, err := h.service.DiscoverFeeds(ctx, pageURL)
+	if err != nil {
+		h.sendMessage(message.Chat.ID, "Не удалось найти RSS-ленту по этой ссылке: "+
+			tgbotapi.EscapeText(tgbotapi.ModeMarkdown, err.Error()))
+		return
+	}
+	h.offerFeeds(ctx, message.Chat.ID, candidates, "", nil)
+}
+
+func (h *Handler) offerFeeds(ctx context.Context, chatID int64, candidates []models.FeedCandidate, name string, categoryID *int64) {
+	token, options := h.service.PrepareFeedChoice(ctx, candidates, name, categoryID)
+
+	var text strings.Builder
+	if len(options) == 1 {
+		text.WriteString("*Найдена лента:*\n\n")
+	} else {
+		text.WriteString("*Найдено несколько лент, выберите нужную:*\n\n")
+	}
+	for i, option := range options {
+		title := option.Title
+		if title == "" {
+			title = "Без названия"
+		}
+		text.WriteString(fmt.Sprintf("%d. %s\n%s\n",
+			i+1,
+			tgbotapi.EscapeText(tgbotapi.ModeMarkdown, title),
+			tgbotapi.EscapeText(tgbotapi.ModeMarkdown, option.URL),
+		))
+		if option.SourceID != 0 {
+			text.WriteString("_уже есть в списке источников_\n")
+		}
+		text.WriteString("\n")
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = FeedChoiceKeyboard(token, options)
+	_, _ = h.bot.Send(msg)
+}
+
+func (h *Handler) handleFeedCallback(ctx context.Context, chatID, userID int64, data string) {
+	subscribe := strings.HasPrefix(data, "feed_sub:")
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 {
+		h.sendMessage(chatID, "Ошибка: неверный выбор ленты")
+		return
+	}
+	index, err := strconv.Atoi(parts[2])
+	if err != nil {
+		h.sendMessage(chatID, "Ошибка: неверный выбор ленты")
+		return
+	}
+
+	source, preview, err := h.service.AddFeed(ctx, parts[1], index, userID, subscribe)
+	if err != nil {
+		h.sendMessage(chatID, "Ошибка: "+tgbotapi.EscapeText(tgbotapi.ModeMarkdown, err.Error()))
+		return
+	}
+
+	name := tgbotapi.EscapeText(tgbotapi.ModeMarkdown, source.Name)
+	if subscribe {
+		h.sendMessage(chatID, fmt.Sprintf("Подписка на «%s» оформлена!", name)+formatFeedPreview(preview))
+		return
+	}
+	h.sendMessage(chatID, fmt.Sprintf("Источник «%s» добавлен, теперь на него могут подписаться все пользователи.", name)+
+		formatFeedPreview(preview))
+}
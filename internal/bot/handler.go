@@ -2,6 +2,7 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/SANEKNAYMCHIK/newsBot/internal/services"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
@@ -99,6 +101,8 @@ func (h *Handler) handleCommand(ctx context.Context, message *tgbotapi.Message,
 		h.handleUpdateCommand(ctx, message, user)
 	case "update_status":
 		h.handleUpdateStatusCommand(ctx, message, user)
+	case "settings":
+		h.handleSettingsCommand(ctx, message, user)
 	default:
 		h.sendMessage(message.Chat.ID, "Неизвестная команда. Используйте /help для списка команд.")
 	}
@@ -144,7 +148,8 @@ func (h *Handler) handleHelp(ctx context.Context, message *tgbotapi.Message) {
 */categories* - Показать все категории
 */add_source* - Добавить новый источник
 */update* - Обновить новости вручную
-*/update_status <id>* - Статус обновления новостей`
+*/update_status <id>* - Статус обновления новостей
+*/settings* - Настройки доставки: сразу или дайджестом`
 
 	if isAdmin {
 		helpText += `*Админские команды:*
@@ -166,6 +171,8 @@ func (h *Handler) handleHelp(ctx context.Context, message *tgbotapi.Message) {
 Добавить подписку - Подписаться на новые источники
 Обновить новости - Обновить ленту вручную
 
+Можно просто прислать ссылку на сайт: бот найдет его RSS-ленту и предложит подписаться.
+
 *Поддержка:*
 Если возникли проблемы, напишите @saneknaumchik`
 
@@ -237,11 +244,13 @@ func (h *Handler) showUserNewsWithPagination(ctx context.Context, chatID, userID
 				"%s\n"+
 				"[Читать статью](%s)",
 			i+1,
-			item.Title,
+			tgbotapi.EscapeText(tgbotapi.ModeMarkdown, item.Title),
 			item.PublishedAt.UTC().Format("02.01.2006 15:04"),
-			item.SourceName,
-			item.URL,
+			tgbotapi.EscapeText(tgbotapi.ModeMarkdown, item.SourceName),
+			escapeLinkURL(item.URL),
 		)
+		text += formatAlsoIn(item)
+		text += formatExcerpt(item.Content)
 
 		msg := tgbotapi.NewMessage(chatID, text)
 		msg.ParseMode = "Markdown"
@@ -421,10 +430,11 @@ func (h *Handler) showSourceNewsWithPagination(ctx context.Context, chatID, user
 				"%s (UTC)\n"+
 				"[Читать статью](%s)",
 			i+1,
-			item.Title,
+			tgbotapi.EscapeText(tgbotapi.ModeMarkdown, item.Title),
 			item.PublishedAt.UTC().Format("02.01.2006 15:04"),
-			item.URL,
+			escapeLinkURL(item.URL),
 		)
+		text += formatExcerpt(item.Content)
 
 		msg := tgbotapi.NewMessage(chatID, text)
 		msg.ParseMode = "Markdown"
@@ -485,6 +495,7 @@ func (h *Handler) handleAddSourceCommand(ctx context.Context, message *tgbotapi.
 				"`/add_source Название; URL; ID_категории`\n\n"+
 				"Пример:\n"+
 				"`/add_source Habr; https://habr.com/ru/rss/articles/; 1`\n\n"+
+				"Название можно оставить пустым - оно будет взято из ленты.\n"+
 				"Для просмотра доступных категорий используйте /categories")
 		return
 	}
@@ -507,13 +518,20 @@ func (h *Handler) handleAddSourceCommand(ctx context.Context, message *tgbotapi.
 		return
 	}
 
-	err = h.service.AddSource(ctx, name, url, categoryID, user.ID)
+	created, err := h.service.AddSource(ctx, name, url, categoryID, user.ID)
+	var choiceErr *services.FeedChoiceError
+	if errors.As(err, &choiceErr) {
+		h.offerFeeds(ctx, message.Chat.ID, choiceErr.Candidates, name, &categoryID)
+		return
+	}
 	if err != nil {
-		h.sendMessage(message.Chat.ID, fmt.Sprintf("Ошибка при добавлении источника: %v", err))
+		h.sendMessage(message.Chat.ID, "Ошибка при добавлении источника: "+
+			tgbotapi.EscapeText(tgbotapi.ModeMarkdown, err.Error()))
 		return
 	}
 
-	h.sendMessage(message.Chat.ID, "Источник успешно добавлен!")
+	h.sendMessage(message.Chat.ID, fmt.Sprintf("Источник «%s» успешно добавлен!",
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdown, created.Name))+formatFeedPreview(created.Preview))
 }
 
 func (h *Handler) handleCategoriesCommand(ctx context.Context, message *tgbotapi.Message) {
@@ -613,6 +631,10 @@ func (h *Handler) handleText(ctx context.Context, message *tgbotapi.Message, use
 	case "Помощь":
 		h.handleHelp(ctx, message)
 	default:
+		if looksLikeURL(message.Text) {
+			h.handleFeedURL(ctx, message)
+			return
+		}
 		h.sendMessage(message.Chat.ID, "Я понимаю только команды и кнопки меню. Используйте /help для списка команд.")
 	}
 }
@@ -668,6 +690,9 @@ func (h *Handler) handleCallbackQuery(ctx context.Context, callback *tgbotapi.Ca
 
 		h.showSubscriptionMenu(ctx, chatID, user.ID)
 
+	case strings.HasPrefix(data, "feed_sub:"), strings.HasPrefix(data, "feed_add:"):
+		h.handleFeedCallback(ctx, chatID, user.ID, data)
+
 	case strings.HasPrefix(data, "news_page:"):
 		pageStr := strings.TrimPrefix(data, "news_page:")
 		page, err := strconv.Atoi(pageStr)
@@ -743,3 +768,18 @@ func (h *Handler) showSubscriptionMenu(ctx context.Context, chatID, userID int64
 	msg.ReplyMarkup = keyboard
 	_, _ = h.bot.Send(msg)
 }
+
+// formatAlsoIn перечисляет другие источники, опубликовавшие ту же новость.
+func formatAlsoIn(item NewsWithSource) string {
+	var links []string
+	for _, other := range item.Sources {
+		if other.NewsID == item.ID {
+			continue
+		}
+		links = append(links, fmt.Sprintf("[%s](%s)", escapeLinkText(other.SourceName), escapeLinkURL(other.URL)))
+	}
+	if len(links) == 0 {
+		return ""
+	}
+	return "\nТакже в: " + strings.Join(links, ", ")
+}
@@ -84,6 +84,25 @@ func CategoryKeyboard(categories []models.Category) tgbotapi.InlineKeyboardMarku
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
+func FeedChoiceKeyboard(token string, options []FeedOption) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	for i, option := range options {
+		if option.SourceID != 0 {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d. Подписаться", i+1), fmt.Sprintf("subscribe:%d", option.SourceID)),
+			))
+			continue
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d. Подписаться", i+1), fmt.Sprintf("feed_sub:%s:%d", token, i)),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d. Предложить источник", i+1), fmt.Sprintf("feed_add:%s:%d", token, i)),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
 func NewsNavigationKeyboard(newsID int64) tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
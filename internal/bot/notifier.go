@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/SANEKNAYMCHIK/newsBot/internal/services"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type Notifier struct {
+	bot *tgbotapi.BotAPI
+}
+
+func NewNotifier(bot *tgbotapi.BotAPI) *Notifier {
+	return &Notifier{bot: bot}
+}
+
+func (n *Notifier) SendNews(ctx context.Context, delivery models.PendingDelivery) error {
+	if len(delivery.News.Media) > 0 {
+		err := n.sendNewsWithMedia(delivery, delivery.News.Media[0])
+		if err == nil || errors.Is(err, services.ErrRecipientUnavailable) {
+			return err
+		}
+		// Telegram не смог загрузить вложение по ссылке - отправляем текстом
+	}
+
+	msg := tgbotapi.NewMessage(delivery.ChatID, formatNewsMessage(delivery, true))
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	_, err := n.bot.Send(msg)
+	return classifySendError(err)
+}
+
+const maxCaptionLength = 1024
+
+// sendNewsWithMedia отправляет новость фотографией или аудиофайлом с подписью.
+func (n *Notifier) sendNewsWithMedia(delivery models.PendingDelivery, media models.NewsMedia) error {
+	caption := formatNewsMessage(delivery, true)
+	if utf8.RuneCountInString(caption) > maxCaptionLength {
+		caption = formatNewsMessage(delivery, false)
+	}
+	file := tgbotapi.FileURL(media.URL)
+
+	var msg tgbotapi.Chattable
+	switch media.Kind {
+	case models.MediaKindImage:
+		photo := tgbotapi.NewPhoto(delivery.ChatID, file)
+		photo.Caption = caption
+		photo.ParseMode = tgbotapi.ModeMarkdown
+		msg = photo
+	case models.MediaKindAudio:
+		audio := tgbotapi.NewAudio(delivery.ChatID, file)
+		audio.Caption = caption
+		audio.ParseMode = tgbotapi.ModeMarkdown
+		msg = audio
+	default:
+		return fmt.Errorf("unsupported media kind %s", media.Kind)
+	}
+
+	_, err := n.bot.Send(msg)
+	return classifySendError(err)
+}
+
+func formatNewsMessage(delivery models.PendingDelivery, withExcerpt bool) string {
+	text := fmt.Sprintf(
+		"*%s*\n\n"+
+			"%s (UTC)\n"+
+			"%s\n"+
+			"[Читать статью](%s)",
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdown, delivery.News.Title),
+		delivery.News.PublishedAt.UTC().Format("02.01.2006 15:04"),
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdown, delivery.SourceName),
+		escapeLinkURL(delivery.News.URL),
+	)
+	if delivery.IsUpdate {
+		text = "_Новость обновлена_\n\n" + text
+	}
+	if withExcerpt && delivery.News.ContentText != nil {
+		text += formatExcerpt(*delivery.News.ContentText)
+	}
+	if delivery.News.ReadingMinutes > 0 {
+		text += fmt.Sprintf("\n\n_Время чтения: %d мин_", delivery.News.ReadingMinutes)
+	}
+	return text
+}
+
+const maxMessageLength = 4000
+
+// SendDigest отправляет дайджест одним или несколькими сообщениями и
+// возвращает ID новостей из отправленных сообщений.
+func (n *Notifier) SendDigest(ctx context.Context, digest models.Digest) ([]int64, error) {
+	header := "*Дайджест новостей*\n"
+	if digest.Since != nil {
+		header = fmt.Sprintf("*Дайджест новостей с %s (UTC)*\n", digest.Since.UTC().Format("02.01.2006 15:04"))
+	}
+
+	type chunk struct {
+		text    string
+		newsIDs []int64
+	}
+	var chunks []chunk
+	var current strings.Builder
+	var currentIDs []int64
+	current.WriteString(header)
+	appendBlock := func(block string, newsID int64) {
+		if current.Len()+len(block) > maxMessageLength {
+			chunks = append(chunks, chunk{text: current.String(), newsIDs: currentIDs})
+			current.Reset()
+			currentIDs = nil
+		}
+		current.WriteString(block)
+		if newsID != 0 {
+			currentIDs = append(currentIDs, newsID)
+		}
+	}
+
+	for _, group := range digest.Groups {
+		appendBlock(fmt.Sprintf("\n*%s*\n", tgbotapi.EscapeText(tgbotapi.ModeMarkdown, group.Name)), 0)
+		for _, item := range group.News {
+			appendBlock(fmt.Sprintf("• [%s](%s)\n",
+				escapeLinkText(item.Title),
+				escapeLinkURL(item.URL),
+			), item.ID)
+		}
+	}
+	chunks = append(chunks, chunk{text: current.String(), newsIDs: currentIDs})
+
+	var sent []int64
+	for _, c := range chunks {
+		msg := tgbotapi.NewMessage(digest.ChatID, c.text)
+		msg.ParseMode = tgbotapi.ModeMarkdown
+		msg.DisableWebPagePreview = true
+		if _, err := n.bot.Send(msg); err != nil {
+			return sent, classifySendError(err)
+		}
+		sent = append(sent, c.newsIDs...)
+	}
+	return sent, nil
+}
+
+const excerptLength = 300
+
+// formatExcerpt возвращает начало текста новости, подготовленное для Markdown.
+func formatExcerpt(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	return "\n\n" + tgbotapi.EscapeText(tgbotapi.ModeMarkdown, truncateText(text, excerptLength))
+}
+
+func escapeLinkText(text string) string {
+	return strings.NewReplacer("[", "(", "]", ")", "*", "", "_", " ", "`", "'").Replace(text)
+}
+
+// linkURLEscaper кодирует символы, на которых Telegram обрывает адрес ссылки
+// в Markdown; для сервера такая ссылка равнозначна исходной.
+var linkURLEscaper = strings.NewReplacer("(", "%28", ")", "%29", "\\", "%5C", "_", "%5F", " ", "%20")
+
+func escapeLinkURL(rawURL string) string {
+	return linkURLEscaper.Replace(rawURL)
+}
+
+func classifySendError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden {
+		return fmt.Errorf("%w: %s", services.ErrRecipientUnavailable, apiErr.Message)
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -23,6 +24,9 @@ type BotService struct {
 	categoryService  *services.CategoryService
 	sourceService    *services.SourceService
 	refreshService   *services.RefreshService
+	settingsService  *services.SettingsService
+
+	feedChoices *feedChoices
 }
 
 type NewsWithSource struct {
@@ -33,6 +37,7 @@ type NewsWithSource struct {
 	PublishedAt time.Time
 	SourceID    int64
 	SourceName  string
+	Sources     []models.NewsSource
 }
 
 func NewBotService(
@@ -47,6 +52,7 @@ func NewBotService(
 	categoryService *services.CategoryService,
 	sourceService *services.SourceService,
 	refreshService *services.RefreshService,
+	settingsService *services.SettingsService,
 ) *BotService {
 	return &BotService{
 		authService:      authService,
@@ -60,6 +66,9 @@ func NewBotService(
 		categoryService:  categoryService,
 		sourceService:    sourceService,
 		refreshService:   refreshService,
+		settingsService:  settingsService,
+
+		feedChoices: newFeedChoices(),
 	}
 }
 
@@ -99,7 +108,7 @@ func (s *BotService) GetNewsForUser(ctx context.Context, userID int64, limit int
 }
 
 func (s *BotService) GetNewsForUserLegacy(ctx context.Context, userID int64, limit int) ([]NewsWithSource, error) {
-	newsItems, _, err := s.newsRepo.GetNewsForUser(ctx, userID, 0, limit)
+	newsItems, _, err := s.newsRepo.GetNewsForUser(ctx, userID, models.NewsFilter{}, 0, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -112,8 +121,8 @@ func (s *BotService) GetNewsForUserLegacy(ctx context.Context, userID int64, lim
 		}
 
 		content := ""
-		if item.Content != nil {
-			content = *item.Content
+		if item.ContentText != nil {
+			content = *item.ContentText
 		}
 
 		result = append(result, NewsWithSource{
@@ -124,6 +133,7 @@ func (s *BotService) GetNewsForUserLegacy(ctx context.Context, userID int64, lim
 			PublishedAt: item.PublishedAt,
 			SourceID:    item.SourceID,
 			SourceName:  source.Name,
+			Sources:     item.Sources,
 		})
 	}
 
@@ -133,7 +143,7 @@ func (s *BotService) GetNewsForUserLegacy(ctx context.Context, userID int64, lim
 func (s *BotService) GetNewsForUserWithPagination(ctx context.Context, userID int64, page, pageSize int) (*models.PaginatedResponse[NewsWithSource], error) {
 	log.Println("GetNewsForUser")
 	// offset := (page - 1) * pageSize
-	newsItems, total, err := s.newsRepo.GetNewsForUser(ctx, userID, page, pageSize)
+	newsItems, total, err := s.newsRepo.GetNewsForUser(ctx, userID, models.NewsFilter{}, page, pageSize)
 	if err != nil {
 		return nil, err
 	}
@@ -147,8 +157,8 @@ func (s *BotService) GetNewsForUserWithPagination(ctx context.Context, userID in
 			continue
 		}
 		content := ""
-		if item.Content != nil {
-			content = *item.Content
+		if item.ContentText != nil {
+			content = *item.ContentText
 		}
 		data = append(data, NewsWithSource{
 			ID:          item.ID,
@@ -158,6 +168,7 @@ func (s *BotService) GetNewsForUserWithPagination(ctx context.Context, userID in
 			PublishedAt: item.PublishedAt,
 			SourceID:    item.SourceID,
 			SourceName:  source.Name,
+			Sources:     item.Sources,
 		})
 		log.Println(data)
 	}
@@ -235,8 +246,8 @@ func (s *BotService) GetNewsBySourceWithPagination(ctx context.Context, sourceID
 	var data []NewsWithSource
 	for _, item := range newsItems {
 		content := ""
-		if item.Content != nil {
-			content = *item.Content
+		if item.ContentText != nil {
+			content = *item.ContentText
 		}
 
 		data = append(data, NewsWithSource{
@@ -321,21 +332,25 @@ func (s *BotService) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	return user.Role == "admin", nil
 }
 
-func (s *BotService) AddSource(ctx context.Context, name, url string, categoryID, userID int64) error {
-	existing, err := s.sourceRepo.GetByURL(ctx, url)
-	if err == nil && existing != nil {
-		return fmt.Errorf("источник с таким URL уже существует")
-	}
-	catID := &categoryID
-	source := &models.Source{
+func (s *BotService) AddSource(ctx context.Context, name, url string, categoryID, userID int64) (*models.CreateSourceResponse, error) {
+	req := &models.CreateSourceRequest{
 		Name:       name,
 		URL:        url,
-		CategoryID: catID,
+		CategoryID: &categoryID,
 		IsActive:   true,
 	}
 
-	err = s.sourceRepo.Create(ctx, source)
-	return err
+	// *services.FeedChoiceError возвращается как есть: обработчик предложит
+	// пользователю выбрать ленту
+	created, err := s.sourceService.CreateSource(ctx, req)
+	if err != nil {
+		var choiceErr *services.FeedChoiceError
+		if errors.As(err, &choiceErr) {
+			return nil, err
+		}
+		return nil, describeSourceError(err)
+	}
+	return created, nil
 }
 
 func (s *BotService) RequestNewsUpdate(ctx context.Context, userID int64) (string, error) {
@@ -346,8 +361,21 @@ func (s *BotService) RequestNewsUpdate(ctx context.Context, userID int64) (strin
 	return req.ID, nil
 }
 
-func (s *BotService) GetUpdateStatus(ctx context.Context, requestID string) (*services.RefreshRequest, bool) {
-	return s.refreshService.GetRequestStatus(requestID)
+func (s *BotService) GetUpdateStatus(ctx context.Context, requestID string) (*models.RefreshRequest, bool) {
+	req, err := s.refreshService.GetRequestStatus(ctx, requestID)
+	if err != nil {
+		log.Printf("Failed to get refresh request %s: %v", requestID, err)
+		return nil, false
+	}
+	return req, req != nil
+}
+
+func (s *BotService) GetSettings(ctx context.Context, userID int64) (*models.UserSettings, error) {
+	return s.settingsService.GetSettings(ctx, userID)
+}
+
+func (s *BotService) UpdateSettings(ctx context.Context, userID int64, req *models.UpdateSettingsRequest) (*models.UserSettings, error) {
+	return s.settingsService.UpdateSettings(ctx, userID, req)
 }
 
 func (s *BotService) GetUsers(ctx context.Context, page, pageSize int) (*models.PaginatedResponse[models.User], error) {
@@ -378,6 +406,10 @@ func (s *BotService) DeleteSource(ctx context.Context, sourceID int) error {
 	return s.sourceService.DeleteSource(ctx, sourceID)
 }
 
+func (s *BotService) GetFailingSources(ctx context.Context, limit int) (*models.PaginatedResponse[models.SourceHealth], error) {
+	return s.sourceService.GetSourcesHealth(ctx, true, 1, limit)
+}
+
 func (s *BotService) GetSystemStats(ctx context.Context) (map[string]interface{}, error) {
 	users, _, err := s.userRepo.GetUsers(ctx, 1, 1)
 	if err != nil {
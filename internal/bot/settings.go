@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var weekdayNames = map[string]int{
+	"пн": 1, "вт": 2, "ср": 3, "чт": 4, "пт": 5, "сб": 6, "вс": 7,
+	"mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6, "sun": 7,
+}
+
+var weekdayTitles = []string{"", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота", "воскресенье"}
+
+const settingsUsage = "*Использование:*\n" +
+	"`/settings instant` - присылать каждую новость сразу\n" +
+	"`/settings hourly` - дайджест раз в час\n" +
+	"`/settings daily 09:00` - ежедневный дайджест в указанное время\n" +
+	"`/settings weekly пн 09:00` - еженедельный дайджест\n" +
+	"`/settings tz Europe/Moscow` - часовой пояс\n" +
+	"`/settings group source` или `/settings group category` - группировка дайджеста"
+
+func (h *Handler) handleSettingsCommand(ctx context.Context, message *tgbotapi.Message, user *models.User) {
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		settings, err := h.service.GetSettings(ctx, user.ID)
+		if err != nil {
+			h.sendMessage(message.Chat.ID, "Ошибка получения настроек")
+			return
+		}
+		h.sendMessage(message.Chat.ID, formatSettings(settings)+"\n\n"+settingsUsage)
+		return
+	}
+
+	req, err := parseSettingsArgs(args)
+	if err != nil {
+		h.sendMessage(message.Chat.ID, fmt.Sprintf("%v\n\n%s", err, settingsUsage))
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(ctx, user.ID, req)
+	if err != nil {
+		h.sendMessage(message.Chat.ID, fmt.Sprintf("Ошибка сохранения настроек: %v", err))
+		return
+	}
+
+	h.sendMessage(message.Chat.ID, "Настройки сохранены!\n\n"+formatSettings(settings))
+}
+
+func parseSettingsArgs(args []string) (*models.UpdateSettingsRequest, error) {
+	req := &models.UpdateSettingsRequest{}
+	mode := strings.ToLower(args[0])
+
+	switch mode {
+	case models.DeliveryModeInstant, models.DeliveryModeHourly:
+		req.DeliveryMode = &mode
+	case models.DeliveryModeDaily:
+		req.DeliveryMode = &mode
+		if len(args) > 1 {
+			req.DigestTime = &args[1]
+		}
+	case models.DeliveryModeWeekly:
+		req.DeliveryMode = &mode
+		if len(args) > 1 {
+			weekday, err := parseWeekday(args[1])
+			if err != nil {
+				return nil, err
+			}
+			req.DigestWeekday = &weekday
+		}
+		if len(args) > 2 {
+			req.DigestTime = &args[2]
+		}
+	case "tz":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("укажите часовой пояс, например Europe/Moscow")
+		}
+		req.Timezone = &args[1]
+	case "group":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("укажите группировку: source или category")
+		}
+		groupBy := strings.ToLower(args[1])
+		if groupBy != models.DigestGroupBySource && groupBy != models.DigestGroupByCategory {
+			return nil, fmt.Errorf("группировка должна быть source или category")
+		}
+		req.GroupBy = &groupBy
+	default:
+		return nil, fmt.Errorf("неизвестный режим: %s", args[0])
+	}
+
+	return req, nil
+}
+
+func parseWeekday(value string) (int, error) {
+	if weekday, ok := weekdayNames[strings.ToLower(value)]; ok {
+		return weekday, nil
+	}
+	weekday, err := strconv.Atoi(value)
+	if err != nil || weekday < 1 || weekday > 7 {
+		return 0, fmt.Errorf("день недели должен быть от 1 (пн) до 7 (вс)")
+	}
+	return weekday, nil
+}
+
+func formatSettings(settings *models.UserSettings) string {
+	var mode string
+	switch settings.DeliveryMode {
+	case models.DeliveryModeHourly:
+		mode = "дайджест раз в час"
+	case models.DeliveryModeDaily:
+		mode = fmt.Sprintf("ежедневный дайджест в %s", settings.DigestTime)
+	case models.DeliveryModeWeekly:
+		mode = fmt.Sprintf("еженедельный дайджест (%s, %s)", weekdayTitles[settings.DigestWeekday], settings.DigestTime)
+	default:
+		mode = "каждая новость сразу"
+	}
+
+	groupBy := "по источникам"
+	if settings.GroupBy == models.DigestGroupByCategory {
+		groupBy = "по категориям"
+	}
+
+	text := "*Настройки доставки*\n\n"
+	text += fmt.Sprintf("Режим: %s\n", mode)
+	text += fmt.Sprintf("Часовой пояс: `%s`\n", settings.Timezone)
+	text += fmt.Sprintf("Группировка дайджеста: %s", groupBy)
+	if settings.NextDigestAt != nil && settings.DeliveryMode != models.DeliveryModeInstant {
+		text += fmt.Sprintf("\nСледующий дайджест: %s (UTC)", settings.NextDigestAt.UTC().Format("02.01.2006 15:04"))
+	}
+	return text
+}
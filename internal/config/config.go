@@ -18,6 +18,50 @@ type Config struct {
 	HTTPSKeyFile   string
 	HTTPSPort      string
 	AllowedOrigins []string
+
+	DeliveryInterval    int
+	DeliveryMaxAttempts int
+	DigestInterval      int
+
+	SourceMaxFailures int
+	FetchTickInterval int
+	// NotifyNewsEdits - повторно отправлять подписчикам существенно исправленные новости
+	NotifyNewsEdits bool
+
+	// Ограничения загрузки лент: таймауты в секундах, размер ответа в байтах,
+	// число обрабатываемых новостей и одновременных запросов к одному хосту
+	FetchConnectTimeout int
+	FetchReadTimeout    int
+	FetchMaxBodyBytes   int
+	FetchMaxItems       int
+	FetchMaxPerHost     int
+
+	// Повторы загрузки лент: число попыток, начальная и максимальная пауза в
+	// секундах, предел отсрочки по Retry-After в секундах
+	FetchMaxAttempts    int
+	FetchRetryBaseDelay int
+	FetchRetryMaxDelay  int
+	FetchRetryAfterMax  int
+
+	// Хранение новостей: срок в днях (0 - хранить всегда), режим archive или
+	// delete, интервал очистки в часах, размер порции удаления и сколько дней
+	// после срока хранения держать ключи дедупликации удаленных новостей
+	RetentionDays         int
+	RetentionMode         string
+	RetentionInterval     int
+	RetentionBatchSize    int
+	RetentionKeyGraceDays int
+
+	// NewsPartitionsAhead - на сколько месяцев вперед создавать секции news_items
+	NewsPartitionsAhead int
+
+	// LeaderHeartbeatInterval - как часто (в секундах) лидер проверяет свою
+	// блокировку, а остальные процессы пытаются ее захватить
+	LeaderHeartbeatInterval int
+
+	// FetchAllowedNetworks - внутренние сети (CIDR или IP), к которым можно
+	// обращаться при загрузке лент и страниц. По умолчанию внутренние адреса запрещены.
+	FetchAllowedNetworks []string
 }
 
 func Load() *Config {
@@ -32,6 +76,37 @@ func Load() *Config {
 		HTTPSKeyFile:     getEnv("HTTPS_KEY_FILE", "ssl/server.key"),
 		HTTPSPort:        getEnv("HTTPS_PORT", "8443"),
 		AllowedOrigins:   getEnvAsSlice("ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
+
+		DeliveryInterval:    getEnvAsInt("DELIVERY_INTERVAL", 15),
+		DeliveryMaxAttempts: getEnvAsInt("DELIVERY_MAX_ATTEMPTS", 5),
+		DigestInterval:      getEnvAsInt("DIGEST_INTERVAL", 60),
+
+		SourceMaxFailures: getEnvAsInt("SOURCE_MAX_FAILURES", 10),
+		FetchTickInterval: getEnvAsInt("FETCH_TICK_INTERVAL", 60),
+		NotifyNewsEdits:   getEnvAsBool("NOTIFY_NEWS_EDITS", false),
+
+		FetchConnectTimeout: getEnvAsInt("FETCH_CONNECT_TIMEOUT", 10),
+		FetchReadTimeout:    getEnvAsInt("FETCH_READ_TIMEOUT", 30),
+		FetchMaxBodyBytes:   getEnvAsInt("FETCH_MAX_BODY_BYTES", 10<<20),
+		FetchMaxItems:       getEnvAsInt("FETCH_MAX_ITEMS", 200),
+		FetchMaxPerHost:     getEnvAsInt("FETCH_MAX_PER_HOST", 2),
+
+		FetchMaxAttempts:    getEnvAsInt("FETCH_MAX_ATTEMPTS", 3),
+		FetchRetryBaseDelay: getEnvAsInt("FETCH_RETRY_BASE_DELAY", 1),
+		FetchRetryMaxDelay:  getEnvAsInt("FETCH_RETRY_MAX_DELAY", 30),
+		FetchRetryAfterMax:  getEnvAsInt("FETCH_RETRY_AFTER_MAX", 21600),
+
+		RetentionDays:         getEnvAsInt("RETENTION_DAYS", 0),
+		RetentionMode:         getEnv("RETENTION_MODE", "archive"),
+		RetentionInterval:     getEnvAsInt("RETENTION_INTERVAL", 24),
+		RetentionBatchSize:    getEnvAsInt("RETENTION_BATCH_SIZE", 1000),
+		RetentionKeyGraceDays: getEnvAsInt("RETENTION_KEY_GRACE_DAYS", 90),
+
+		NewsPartitionsAhead: getEnvAsInt("NEWS_PARTITIONS_AHEAD", 3),
+
+		LeaderHeartbeatInterval: getEnvAsInt("LEADER_HEARTBEAT_INTERVAL", 10),
+
+		FetchAllowedNetworks: getEnvAsSlice("FETCH_ALLOWED_NETWORKS", nil),
 	}
 }
 
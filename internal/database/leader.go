@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LeaderElector выбирает среди запущенных процессов одного лидера с помощью
+// сессионной advisory-блокировки Postgres. Блокировка держится на отдельном
+// соединении из пула: если процесс лидера падает, сессия закрывается и
+// блокировку забирает один из ожидающих процессов.
+type LeaderElector struct {
+	pool *pgxpool.Pool
+	name string
+	key  int64
+
+	heartbeatInterval time.Duration
+	retryInterval     time.Duration
+}
+
+func NewLeaderElector(pool *pgxpool.Pool, name string, heartbeatInterval time.Duration) *LeaderElector {
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = 10 * time.Second
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return &LeaderElector{
+		pool:              pool,
+		name:              name,
+		key:               int64(hash.Sum64()),
+		heartbeatInterval: heartbeatInterval,
+		retryInterval:     heartbeatInterval,
+	}
+}
+
+// Run пытается стать лидером, пока не отменен ctx. Став лидером, вызывает
+// lead с контекстом, который отменяется при потере лидерства, и ждет его
+// завершения. lead должен работать, пока его контекст не отменен.
+func (e *LeaderElector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	for {
+		conn, err := e.tryAcquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Leader election %s failed: %v", e.name, err)
+		}
+		if conn != nil {
+			log.Printf("Became leader of %s", e.name)
+			e.lead(ctx, conn, lead)
+			log.Printf("Stepped down as leader of %s", e.name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.retryInterval):
+		}
+	}
+}
+
+func (e *LeaderElector) tryAcquire(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := e.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", e.key).Scan(&acquired); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	if !acquired {
+		conn.Release()
+		return nil, nil
+	}
+	return conn, nil
+}
+
+func (e *LeaderElector) lead(ctx context.Context, conn *pgxpool.Conn, lead func(ctx context.Context)) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leaderCtx)
+	}()
+
+	e.heartbeat(leaderCtx, conn, done)
+	cancel()
+	<-done
+	e.release(conn)
+}
+
+// heartbeat проверяет, что соединение живо и блокировка все еще принадлежит
+// ему, и возвращается, как только это не так.
+func (e *LeaderElector) heartbeat(ctx context.Context, conn *pgxpool.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(e.heartbeatInterval)
+	defer ticker.Stop()
+
+	query := `
+        SELECT EXISTS (
+            SELECT 1 FROM pg_locks
+            WHERE locktype = 'advisory' AND objsubid = 1 AND pid = pg_backend_pid() AND granted
+              AND ((classid::bigint << 32) | objid::bigint) = $1
+        )
+    `
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, e.heartbeatInterval)
+			var held bool
+			err := conn.QueryRow(checkCtx, query, e.key).Scan(&held)
+			cancel()
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Leader heartbeat for %s failed: %v", e.name, err)
+				return
+			}
+			if !held && ctx.Err() == nil {
+				log.Printf("Leader lock for %s was lost", e.name)
+				return
+			}
+		}
+	}
+}
+
+// release снимает блокировку. Если это не удалось, соединение закрывается,
+// чтобы Postgres освободил блокировку вместе с сессией.
+func (e *LeaderElector) release(conn *pgxpool.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", e.key); err != nil {
+		log.Printf("Failed to release leader lock for %s: %v", e.name, err)
+		conn.Conn().Close(ctx)
+	}
+	conn.Release()
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS news_deliveries;
\ No newline at end of file
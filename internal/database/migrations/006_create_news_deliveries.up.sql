@@ -0,0 +1,16 @@
+CREATE TABLE news_deliveries (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    news_item_id INTEGER REFERENCES news_items(id) ON DELETE CASCADE NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending' NOT NULL,
+    attempts INTEGER DEFAULT 0 NOT NULL,
+    next_attempt_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    claimed_at TIMESTAMPTZ,
+    sent_at TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    UNIQUE(user_id, news_item_id)
+);
+
+CREATE INDEX idx_news_deliveries_pending ON news_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_news_deliveries_processing ON news_deliveries(claimed_at) WHERE status = 'processing';
\ No newline at end of file
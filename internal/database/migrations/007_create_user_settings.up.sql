@@ -0,0 +1,12 @@
+CREATE TABLE user_settings (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    delivery_mode VARCHAR(20) DEFAULT 'instant' NOT NULL,
+    digest_time VARCHAR(5) DEFAULT '09:00' NOT NULL,
+    digest_weekday SMALLINT DEFAULT 1 NOT NULL,
+    timezone VARCHAR(64) DEFAULT 'UTC' NOT NULL,
+    group_by VARCHAR(20) DEFAULT 'source' NOT NULL,
+    last_digest_at TIMESTAMPTZ,
+    next_digest_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_user_settings_next_digest ON user_settings(next_digest_at) WHERE delivery_mode <> 'instant';
\ No newline at end of file
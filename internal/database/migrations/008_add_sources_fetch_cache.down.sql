@@ -0,0 +1,3 @@
+ALTER TABLE sources
+    DROP COLUMN IF EXISTS etag,
+    DROP COLUMN IF EXISTS last_modified;
\ No newline at end of file
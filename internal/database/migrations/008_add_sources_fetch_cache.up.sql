@@ -0,0 +1,3 @@
+ALTER TABLE sources
+    ADD COLUMN etag VARCHAR(500),
+    ADD COLUMN last_modified VARCHAR(100);
\ No newline at end of file
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_sources_failures;
+
+ALTER TABLE sources
+    DROP COLUMN IF EXISTS last_fetched_at,
+    DROP COLUMN IF EXISTS last_success_at,
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS consecutive_failures,
+    DROP COLUMN IF EXISTS last_http_status,
+    DROP COLUMN IF EXISTS avg_fetch_latency_ms;
\ No newline at end of file
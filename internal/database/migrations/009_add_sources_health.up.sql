@@ -0,0 +1,9 @@
+ALTER TABLE sources
+    ADD COLUMN last_fetched_at TIMESTAMPTZ,
+    ADD COLUMN last_success_at TIMESTAMPTZ,
+    ADD COLUMN last_error TEXT,
+    ADD COLUMN consecutive_failures INTEGER DEFAULT 0 NOT NULL,
+    ADD COLUMN last_http_status INTEGER,
+    ADD COLUMN avg_fetch_latency_ms INTEGER;
+
+CREATE INDEX idx_sources_failures ON sources(consecutive_failures) WHERE consecutive_failures > 0;
\ No newline at end of file
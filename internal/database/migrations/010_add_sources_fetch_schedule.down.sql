@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_sources_next_fetch;
+
+ALTER TABLE sources
+    DROP COLUMN IF EXISTS fetch_interval_seconds,
+    DROP COLUMN IF EXISTS min_fetch_interval_seconds,
+    DROP COLUMN IF EXISTS max_fetch_interval_seconds,
+    DROP COLUMN IF EXISTS next_fetch_at;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE sources
+    ADD COLUMN fetch_interval_seconds INTEGER,
+    ADD COLUMN min_fetch_interval_seconds INTEGER DEFAULT 300 NOT NULL,
+    ADD COLUMN max_fetch_interval_seconds INTEGER DEFAULT 86400 NOT NULL,
+    ADD COLUMN next_fetch_at TIMESTAMPTZ;
+
+CREATE INDEX idx_sources_next_fetch ON sources(next_fetch_at) WHERE is_active = true;
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_news_duplicate_of;
+
+ALTER TABLE news_items
+    DROP COLUMN IF EXISTS duplicate_of,
+    DROP COLUMN IF EXISTS fingerprint;
\ No newline at end of file
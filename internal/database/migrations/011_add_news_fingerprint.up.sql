@@ -0,0 +1,5 @@
+ALTER TABLE news_items
+    ADD COLUMN fingerprint BIGINT,
+    ADD COLUMN duplicate_of INTEGER REFERENCES news_items(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_news_duplicate_of ON news_items(duplicate_of) WHERE duplicate_of IS NOT NULL;
\ No newline at end of file
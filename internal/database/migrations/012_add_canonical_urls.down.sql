@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_news_canonical_url;
+ALTER TABLE news_items DROP COLUMN IF EXISTS canonical_url;
+
+DROP INDEX IF EXISTS idx_sources_canonical_url;
+ALTER TABLE sources DROP COLUMN IF EXISTS canonical_url;
\ No newline at end of file
@@ -0,0 +1,9 @@
+ALTER TABLE sources ADD COLUMN canonical_url VARCHAR(500);
+UPDATE sources SET canonical_url = url;
+ALTER TABLE sources ALTER COLUMN canonical_url SET NOT NULL;
+CREATE UNIQUE INDEX idx_sources_canonical_url ON sources(canonical_url);
+
+ALTER TABLE news_items ADD COLUMN canonical_url VARCHAR(500);
+UPDATE news_items SET canonical_url = url;
+ALTER TABLE news_items ALTER COLUMN canonical_url SET NOT NULL;
+CREATE UNIQUE INDEX idx_news_canonical_url ON news_items(canonical_url);
\ No newline at end of file
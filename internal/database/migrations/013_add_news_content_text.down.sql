@@ -0,0 +1,4 @@
+ALTER TABLE news_items
+    DROP COLUMN IF EXISTS content_text,
+    DROP COLUMN IF EXISTS word_count,
+    DROP COLUMN IF EXISTS reading_time_minutes;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE news_items
+    ADD COLUMN content_text TEXT,
+    ADD COLUMN word_count INTEGER DEFAULT 0 NOT NULL,
+    ADD COLUMN reading_time_minutes INTEGER DEFAULT 0 NOT NULL;
\ No newline at end of file
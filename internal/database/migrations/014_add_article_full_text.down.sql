@@ -0,0 +1,6 @@
+ALTER TABLE news_items
+    DROP COLUMN IF EXISTS article_html,
+    DROP COLUMN IF EXISTS article_text;
+
+ALTER TABLE sources
+    DROP COLUMN IF EXISTS fetch_full_text;
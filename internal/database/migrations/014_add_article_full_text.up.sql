@@ -0,0 +1,6 @@
+ALTER TABLE sources
+    ADD COLUMN fetch_full_text BOOLEAN DEFAULT false NOT NULL;
+
+ALTER TABLE news_items
+    ADD COLUMN article_html TEXT,
+    ADD COLUMN article_text TEXT;
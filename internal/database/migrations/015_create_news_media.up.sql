@@ -0,0 +1,12 @@
+CREATE TABLE news_media (
+    id BIGSERIAL PRIMARY KEY,
+    news_item_id INTEGER REFERENCES news_items(id) ON DELETE CASCADE NOT NULL,
+    url VARCHAR(1000) NOT NULL,
+    mime_type VARCHAR(100),
+    size_bytes BIGINT,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('image', 'audio', 'video')),
+    position INTEGER DEFAULT 0 NOT NULL,
+    UNIQUE(news_item_id, url)
+);
+
+CREATE INDEX idx_news_media_news ON news_media(news_item_id, position);
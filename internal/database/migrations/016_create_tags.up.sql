@@ -0,0 +1,15 @@
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) NOT NULL,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('category', 'author')),
+    UNIQUE(kind, slug)
+);
+
+CREATE TABLE news_item_tags (
+    news_item_id INTEGER REFERENCES news_items(id) ON DELETE CASCADE NOT NULL,
+    tag_id INTEGER REFERENCES tags(id) ON DELETE CASCADE NOT NULL,
+    PRIMARY KEY (news_item_id, tag_id)
+);
+
+CREATE INDEX idx_news_item_tags_tag ON news_item_tags(tag_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_news_fetched;
+
+ALTER TABLE news_items
+    DROP COLUMN IF EXISTS fetched_at;
@@ -0,0 +1,6 @@
+ALTER TABLE news_items
+    ADD COLUMN fetched_at TIMESTAMPTZ DEFAULT NOW() NOT NULL;
+
+UPDATE news_items SET fetched_at = LEAST(published_at, fetched_at);
+
+CREATE INDEX idx_news_fetched ON news_items(fetched_at DESC);
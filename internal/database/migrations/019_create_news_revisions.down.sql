@@ -0,0 +1,9 @@
+ALTER TABLE news_deliveries
+    DROP COLUMN IF EXISTS is_update;
+
+DROP TABLE IF EXISTS news_item_revisions;
+
+ALTER TABLE news_items
+    DROP COLUMN IF EXISTS content_hash,
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS revision_count;
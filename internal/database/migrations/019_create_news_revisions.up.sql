@@ -0,0 +1,19 @@
+ALTER TABLE news_items
+    ADD COLUMN content_hash VARCHAR(64),
+    ADD COLUMN updated_at TIMESTAMPTZ,
+    ADD COLUMN revision_count INTEGER DEFAULT 0 NOT NULL;
+
+CREATE TABLE news_item_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    news_item_id INTEGER REFERENCES news_items(id) ON DELETE CASCADE NOT NULL,
+    title VARCHAR(500) NOT NULL,
+    content TEXT,
+    content_text TEXT,
+    content_hash VARCHAR(64) NOT NULL,
+    revised_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX idx_news_revisions_news ON news_item_revisions(news_item_id, revised_at DESC);
+
+ALTER TABLE news_deliveries
+    ADD COLUMN is_update BOOLEAN DEFAULT false NOT NULL;
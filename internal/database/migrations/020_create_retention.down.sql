@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_news_fetched_source;
+DROP TABLE IF EXISTS retention_runs;
+DROP TABLE IF EXISTS news_items_archive;
+
+ALTER TABLE sources
+    DROP COLUMN IF EXISTS retention_days;
+
+DROP TABLE IF EXISTS news_bookmarks;
@@ -0,0 +1,40 @@
+CREATE TABLE news_bookmarks (
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    news_item_id INTEGER REFERENCES news_items(id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (user_id, news_item_id)
+);
+
+CREATE INDEX idx_news_bookmarks_news ON news_bookmarks(news_item_id);
+
+ALTER TABLE sources
+    ADD COLUMN retention_days INTEGER CHECK (retention_days >= 0);
+
+CREATE TABLE news_items_archive (
+    id INTEGER PRIMARY KEY,
+    title VARCHAR(500) NOT NULL,
+    content TEXT,
+    content_text TEXT,
+    article_text TEXT,
+    url VARCHAR(500) NOT NULL,
+    published_at TIMESTAMPTZ NOT NULL,
+    fetched_at TIMESTAMPTZ NOT NULL,
+    source_id INTEGER NOT NULL,
+    guid VARCHAR(500) NOT NULL,
+    archived_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX idx_news_archive_source ON news_items_archive(source_id, published_at DESC);
+
+CREATE TABLE retention_runs (
+    id BIGSERIAL PRIMARY KEY,
+    trigger VARCHAR(20) NOT NULL CHECK (trigger IN ('schedule', 'manual')),
+    mode VARCHAR(20) NOT NULL CHECK (mode IN ('archive', 'delete')),
+    started_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    finished_at TIMESTAMPTZ,
+    removed_count BIGINT DEFAULT 0 NOT NULL,
+    archived_count BIGINT DEFAULT 0 NOT NULL,
+    error TEXT
+);
+
+CREATE INDEX idx_news_fetched_source ON news_items(source_id, fetched_at);
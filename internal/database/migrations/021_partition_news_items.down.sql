@@ -0,0 +1,73 @@
+ALTER TABLE news_items RENAME TO news_items_partitioned;
+ALTER INDEX news_items_pkey RENAME TO news_items_partitioned_pkey;
+
+CREATE TABLE news_items (
+    id INTEGER PRIMARY KEY DEFAULT nextval('news_items_id_seq'),
+    title VARCHAR(500) NOT NULL,
+    content TEXT,
+    url VARCHAR(500) UNIQUE NOT NULL,
+    published_at TIMESTAMPTZ NOT NULL,
+    source_id INTEGER REFERENCES sources(id) ON DELETE CASCADE NOT NULL,
+    guid VARCHAR(500) NOT NULL,
+    fingerprint BIGINT,
+    duplicate_of INTEGER,
+    canonical_url VARCHAR(500) NOT NULL,
+    content_text TEXT,
+    word_count INTEGER DEFAULT 0 NOT NULL,
+    reading_time_minutes INTEGER DEFAULT 0 NOT NULL,
+    article_html TEXT,
+    article_text TEXT,
+    fetched_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    content_hash VARCHAR(64),
+    updated_at TIMESTAMPTZ,
+    revision_count INTEGER DEFAULT 0 NOT NULL,
+    UNIQUE(source_id, guid)
+);
+
+INSERT INTO news_items
+(id, title, content, url, published_at, source_id, guid, fingerprint, duplicate_of, canonical_url,
+ content_text, word_count, reading_time_minutes, article_html, article_text, fetched_at,
+ content_hash, updated_at, revision_count)
+SELECT id, title, content, url, published_at, source_id, guid, fingerprint, duplicate_of, canonical_url,
+       content_text, word_count, reading_time_minutes, article_html, article_text, fetched_at,
+       content_hash, updated_at, revision_count
+FROM news_items_partitioned;
+
+ALTER TABLE news_item_keys ALTER COLUMN id DROP DEFAULT;
+ALTER SEQUENCE news_items_id_seq OWNED BY news_items.id;
+
+ALTER TABLE news_items
+    ADD CONSTRAINT news_items_duplicate_of_fkey
+        FOREIGN KEY (duplicate_of) REFERENCES news_items(id) ON DELETE SET NULL;
+
+ALTER TABLE news_deliveries
+    DROP CONSTRAINT news_deliveries_news_item_id_fkey,
+    ADD CONSTRAINT news_deliveries_news_item_id_fkey
+        FOREIGN KEY (news_item_id) REFERENCES news_items(id) ON DELETE CASCADE;
+ALTER TABLE news_media
+    DROP CONSTRAINT news_media_news_item_id_fkey,
+    ADD CONSTRAINT news_media_news_item_id_fkey
+        FOREIGN KEY (news_item_id) REFERENCES news_items(id) ON DELETE CASCADE;
+ALTER TABLE news_item_tags
+    DROP CONSTRAINT news_item_tags_news_item_id_fkey,
+    ADD CONSTRAINT news_item_tags_news_item_id_fkey
+        FOREIGN KEY (news_item_id) REFERENCES news_items(id) ON DELETE CASCADE;
+ALTER TABLE news_item_revisions
+    DROP CONSTRAINT news_item_revisions_news_item_id_fkey,
+    ADD CONSTRAINT news_item_revisions_news_item_id_fkey
+        FOREIGN KEY (news_item_id) REFERENCES news_items(id) ON DELETE CASCADE;
+ALTER TABLE news_bookmarks
+    DROP CONSTRAINT news_bookmarks_news_item_id_fkey,
+    ADD CONSTRAINT news_bookmarks_news_item_id_fkey
+        FOREIGN KEY (news_item_id) REFERENCES news_items(id) ON DELETE CASCADE;
+
+DROP TABLE news_items_partitioned;
+DROP FUNCTION IF EXISTS create_news_items_partitions(TIMESTAMPTZ, TIMESTAMPTZ);
+DROP TABLE news_item_keys;
+
+CREATE UNIQUE INDEX idx_news_canonical_url ON news_items(canonical_url);
+CREATE INDEX idx_news_source ON news_items(source_id);
+CREATE INDEX idx_news_published ON news_items(published_at DESC);
+CREATE INDEX idx_news_duplicate_of ON news_items(duplicate_of) WHERE duplicate_of IS NOT NULL;
+CREATE INDEX idx_news_fetched ON news_items(fetched_at DESC);
+CREATE INDEX idx_news_fetched_source ON news_items(source_id, fetched_at);
@@ -0,0 +1,133 @@
+-- Уникальность в секционированной таблице проверяется только внутри секции,
+-- поэтому идентификатор новости и ключи дедупликации хранятся отдельно
+CREATE TABLE news_item_keys (
+    id INTEGER PRIMARY KEY,
+    source_id INTEGER REFERENCES sources(id) ON DELETE CASCADE NOT NULL,
+    guid VARCHAR(500) NOT NULL,
+    url VARCHAR(500) NOT NULL,
+    canonical_url VARCHAR(500) NOT NULL,
+    published_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(source_id, guid),
+    UNIQUE(url)
+);
+
+CREATE UNIQUE INDEX idx_news_keys_canonical_url ON news_item_keys(canonical_url);
+
+INSERT INTO news_item_keys (id, source_id, guid, url, canonical_url, published_at)
+SELECT id, source_id, guid, url, canonical_url, published_at FROM news_items;
+
+ALTER TABLE news_items ALTER COLUMN id DROP DEFAULT;
+ALTER SEQUENCE news_items_id_seq OWNED BY news_item_keys.id;
+ALTER TABLE news_item_keys ALTER COLUMN id SET DEFAULT nextval('news_items_id_seq');
+
+ALTER TABLE news_deliveries
+    DROP CONSTRAINT news_deliveries_news_item_id_fkey,
+    ADD CONSTRAINT news_deliveries_news_item_id_fkey
+        FOREIGN KEY (news_item_id) REFERENCES news_item_keys(id) ON DELETE CASCADE;
+ALTER TABLE news_media
+    DROP CONSTRAINT news_media_news_item_id_fkey,
+    ADD CONSTRAINT news_media_news_item_id_fkey
+        FOREIGN KEY (news_item_id) REFERENCES news_item_keys(id) ON DELETE CASCADE;
+ALTER TABLE news_item_tags
+    DROP CONSTRAINT news_item_tags_news_item_id_fkey,
+    ADD CONSTRAINT news_item_tags_news_item_id_fkey
+        FOREIGN KEY (news_item_id) REFERENCES news_item_keys(id) ON DELETE CASCADE;
+ALTER TABLE news_item_revisions
+    DROP CONSTRAINT news_item_revisions_news_item_id_fkey,
+    ADD CONSTRAINT news_item_revisions_news_item_id_fkey
+        FOREIGN KEY (news_item_id) REFERENCES news_item_keys(id) ON DELETE CASCADE;
+ALTER TABLE news_bookmarks
+    DROP CONSTRAINT news_bookmarks_news_item_id_fkey,
+    ADD CONSTRAINT news_bookmarks_news_item_id_fkey
+        FOREIGN KEY (news_item_id) REFERENCES news_item_keys(id) ON DELETE CASCADE;
+
+ALTER TABLE news_items RENAME TO news_items_legacy;
+
+CREATE TABLE news_items (
+    id INTEGER REFERENCES news_item_keys(id) ON DELETE CASCADE NOT NULL,
+    title VARCHAR(500) NOT NULL,
+    content TEXT,
+    url VARCHAR(500) NOT NULL,
+    published_at TIMESTAMPTZ NOT NULL,
+    source_id INTEGER REFERENCES sources(id) ON DELETE CASCADE NOT NULL,
+    guid VARCHAR(500) NOT NULL,
+    fingerprint BIGINT,
+    duplicate_of INTEGER REFERENCES news_item_keys(id) ON DELETE SET NULL,
+    canonical_url VARCHAR(500) NOT NULL,
+    content_text TEXT,
+    word_count INTEGER DEFAULT 0 NOT NULL,
+    reading_time_minutes INTEGER DEFAULT 0 NOT NULL,
+    article_html TEXT,
+    article_text TEXT,
+    fetched_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    content_hash VARCHAR(64),
+    updated_at TIMESTAMPTZ,
+    revision_count INTEGER DEFAULT 0 NOT NULL
+) PARTITION BY RANGE (published_at);
+
+-- Сюда попадают новости вне созданных месячных секций
+CREATE TABLE news_items_default PARTITION OF news_items DEFAULT;
+
+-- Создает месячные секции (по UTC), пересекающиеся с [start_at, end_at], и
+-- переносит в них новости этих месяцев из секции по умолчанию. Возвращает
+-- число созданных секций.
+CREATE FUNCTION create_news_items_partitions(start_at TIMESTAMPTZ, end_at TIMESTAMPTZ)
+RETURNS INTEGER AS $$
+DECLARE
+    month_start TIMESTAMP := date_trunc('month', start_at AT TIME ZONE 'UTC');
+    lower_bound TIMESTAMPTZ;
+    upper_bound TIMESTAMPTZ;
+    partition_name TEXT;
+    created INTEGER := 0;
+BEGIN
+    WHILE month_start <= end_at AT TIME ZONE 'UTC' LOOP
+        partition_name := 'news_items_' || to_char(month_start, 'YYYY_MM');
+        lower_bound := month_start AT TIME ZONE 'UTC';
+        upper_bound := (month_start + INTERVAL '1 month') AT TIME ZONE 'UTC';
+
+        IF to_regclass(partition_name) IS NULL THEN
+            EXECUTE format('CREATE TABLE %I (LIKE news_items INCLUDING DEFAULTS INCLUDING CONSTRAINTS)', partition_name);
+            EXECUTE format(
+                'WITH moved AS (DELETE FROM news_items_default WHERE published_at >= %L AND published_at < %L RETURNING *)
+                 INSERT INTO %I SELECT * FROM moved',
+                lower_bound, upper_bound, partition_name);
+            EXECUTE format('ALTER TABLE news_items ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)',
+                partition_name, lower_bound, upper_bound);
+            created := created + 1;
+        END IF;
+
+        month_start := month_start + INTERVAL '1 month';
+    END LOOP;
+    RETURN created;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Секции создаются только за последний год: новости старше (в том числе с
+-- ошибочными датами) остаются в секции по умолчанию
+SELECT create_news_items_partitions(
+    GREATEST(
+        COALESCE((SELECT MIN(published_at) FROM news_items_legacy), NOW()),
+        NOW() - INTERVAL '12 months'
+    ),
+    NOW() + INTERVAL '3 months'
+);
+
+INSERT INTO news_items
+(id, title, content, url, published_at, source_id, guid, fingerprint, duplicate_of, canonical_url,
+ content_text, word_count, reading_time_minutes, article_html, article_text, fetched_at,
+ content_hash, updated_at, revision_count)
+SELECT id, title, content, url, published_at, source_id, guid, fingerprint, duplicate_of, canonical_url,
+       content_text, word_count, reading_time_minutes, article_html, article_text, fetched_at,
+       content_hash, updated_at, revision_count
+FROM news_items_legacy;
+
+DROP TABLE news_items_legacy;
+
+ALTER TABLE news_items ADD PRIMARY KEY (id, published_at);
+
+CREATE INDEX idx_news_source ON news_items(source_id);
+CREATE INDEX idx_news_published ON news_items(published_at DESC);
+CREATE INDEX idx_news_duplicate_of ON news_items(duplicate_of) WHERE duplicate_of IS NOT NULL;
+CREATE INDEX idx_news_fetched ON news_items(fetched_at DESC);
+CREATE INDEX idx_news_fetched_source ON news_items(source_id, fetched_at);
+CREATE INDEX idx_news_source_guid ON news_items(source_id, guid);
@@ -0,0 +1,16 @@
+-- Одна статья может приходить из нескольких лент: каждая лента ссылается на
+-- общую новость со своим guid и ссылкой
+CREATE TABLE news_item_sources (
+    news_item_id INTEGER REFERENCES news_item_keys(id) ON DELETE CASCADE NOT NULL,
+    source_id INTEGER REFERENCES sources(id) ON DELETE CASCADE NOT NULL,
+    guid VARCHAR(500) NOT NULL,
+    url VARCHAR(500) NOT NULL,
+    linked_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (news_item_id, source_id),
+    UNIQUE(source_id, guid)
+);
+
+CREATE INDEX idx_news_item_sources_source ON news_item_sources(source_id, news_item_id);
+
+INSERT INTO news_item_sources (news_item_id, source_id, guid, url)
+SELECT id, source_id, guid, url FROM news_item_keys;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS refresh_cooldowns;
+DROP TABLE IF EXISTS refresh_jobs;
@@ -0,0 +1,20 @@
+CREATE TABLE refresh_jobs (
+    id UUID PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    status VARCHAR(20) DEFAULT 'queued' NOT NULL
+        CHECK (status IN ('queued', 'processing', 'completed', 'failed')),
+    result INTEGER DEFAULT 0 NOT NULL,
+    error TEXT,
+    attempts INTEGER DEFAULT 0 NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    claimed_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_refresh_jobs_queued ON refresh_jobs(created_at) WHERE status = 'queued';
+CREATE INDEX idx_refresh_jobs_processing ON refresh_jobs(claimed_at) WHERE status = 'processing';
+
+CREATE TABLE refresh_cooldowns (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    last_requested_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,5 @@
+-- Разовые пересчеты данных, которые выполняются кодом приложения после миграций
+CREATE TABLE data_backfills (
+    name VARCHAR(100) PRIMARY KEY,
+    finished_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
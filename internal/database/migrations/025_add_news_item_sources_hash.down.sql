@@ -0,0 +1 @@
+ALTER TABLE news_item_sources DROP COLUMN IF EXISTS content_hash;
@@ -0,0 +1,8 @@
+-- Хеш заголовка и текста статьи в каждой ленте: правка отслеживается по
+-- изменению хеша в той же ленте, а не по расхождению текстов разных лент
+ALTER TABLE news_item_sources ADD COLUMN content_hash VARCHAR(64);
+
+UPDATE news_item_sources ms
+SET content_hash = ni.content_hash
+FROM news_items ni
+WHERE ni.id = ms.news_item_id AND ni.source_id = ms.source_id;
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_retention_runs_requested;
+DROP INDEX IF EXISTS idx_retention_runs_queued;
+
+DELETE FROM retention_runs WHERE started_at IS NULL;
+
+ALTER TABLE retention_runs
+    ALTER COLUMN started_at SET DEFAULT NOW(),
+    ALTER COLUMN started_at SET NOT NULL,
+    DROP COLUMN IF EXISTS requested_at,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,23 @@
+-- Ручной запуск очистки ставится в очередь и выполняется лидером: started_at
+-- заполняется, когда лидер забирает запуск
+ALTER TABLE retention_runs
+    ADD COLUMN status VARCHAR(20) DEFAULT 'completed' NOT NULL
+        CHECK (status IN ('queued', 'running', 'completed', 'failed')),
+    ADD COLUMN requested_at TIMESTAMPTZ;
+
+UPDATE retention_runs
+SET requested_at = started_at,
+    status = CASE WHEN finished_at IS NULL OR error IS NOT NULL THEN 'failed' ELSE 'completed' END,
+    finished_at = COALESCE(finished_at, started_at),
+    error = CASE WHEN finished_at IS NULL THEN COALESCE(error, 'interrupted') ELSE error END;
+
+ALTER TABLE retention_runs
+    ALTER COLUMN requested_at SET DEFAULT NOW(),
+    ALTER COLUMN requested_at SET NOT NULL,
+    ALTER COLUMN status SET DEFAULT 'queued',
+    ALTER COLUMN started_at DROP DEFAULT,
+    ALTER COLUMN started_at DROP NOT NULL;
+
+-- В очереди не больше одного запуска: повторный запрос возвращает его же
+CREATE UNIQUE INDEX idx_retention_runs_queued ON retention_runs(status) WHERE status = 'queued';
+CREATE INDEX idx_retention_runs_requested ON retention_runs(requested_at DESC);
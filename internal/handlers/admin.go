@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -11,20 +12,23 @@ import (
 )
 
 type AdminHandler struct {
-	AdminService    *services.AdminService
-	SourceService   *services.SourceService
-	CategoryService *services.CategoryService
+	AdminService     *services.AdminService
+	SourceService    *services.SourceService
+	CategoryService  *services.CategoryService
+	RetentionService *services.RetentionService
 }
 
 func NewAdminHandler(
 	AdminService *services.AdminService,
 	SourceService *services.SourceService,
 	CategoryService *services.CategoryService,
+	RetentionService *services.RetentionService,
 ) *AdminHandler {
 	return &AdminHandler{
-		AdminService:    AdminService,
-		SourceService:   SourceService,
-		CategoryService: CategoryService,
+		AdminService:     AdminService,
+		SourceService:    SourceService,
+		CategoryService:  CategoryService,
+		RetentionService: RetentionService,
 	}
 }
 
@@ -40,6 +44,19 @@ func (a *AdminHandler) GetUsers(c *gin.Context) {
 	c.JSON(http.StatusOK, users)
 }
 
+func (a *AdminHandler) GetSourcesHealth(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	onlyFailing, _ := strconv.ParseBool(c.DefaultQuery("failing", "false"))
+
+	health, err := a.SourceService.GetSourcesHealth(c.Request.Context(), onlyFailing, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, health)
+}
+
 func (a *AdminHandler) UpdateSource(c *gin.Context) {
 	sourceID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -54,6 +71,22 @@ func (a *AdminHandler) UpdateSource(c *gin.Context) {
 	}
 
 	source, err := a.SourceService.UpdateSource(c.Request.Context(), sourceID, &req)
+	var choiceErr *services.FeedChoiceError
+	if errors.As(err, &choiceErr) {
+		c.JSON(http.StatusMultipleChoices, models.FeedChoiceResponse{
+			Error:      err.Error(),
+			Candidates: choiceErr.Candidates,
+		})
+		return
+	}
+	if errors.Is(err, services.ErrSourceAlreadyExists) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidFeed) || errors.Is(err, services.ErrFeedNotFound) {
+		c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 		return
@@ -78,6 +111,45 @@ func (a *AdminHandler) DeleteSource(c *gin.Context) {
 	c.JSON(http.StatusOK, models.MessageResponse{Message: "source deleted"})
 }
 
+func (a *AdminHandler) GetRetention(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	status, err := a.RetentionService.GetStatus(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (a *AdminHandler) RunRetention(c *gin.Context) {
+	run, err := a.RetentionService.RequestRun(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, run)
+}
+
+func (a *AdminHandler) GetRetentionRun(c *gin.Context) {
+	runID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid run id"})
+		return
+	}
+
+	run, err := a.RetentionService.GetRun(c.Request.Context(), runID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if run == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "retention run not found"})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
 func (a *AdminHandler) AddCategory(c *gin.Context) {
 	var req models.CreateCategoryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
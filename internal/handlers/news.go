@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -36,7 +37,17 @@ func (n *NewsHandler) GetNews(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
 
-	news, err := n.NewsService.GetNews(c.Request.Context(), userID.(int64), page, pageSize)
+	filter := models.NewsFilter{
+		Tag:    c.Query("tag"),
+		Author: c.Query("author"),
+		Sort:   c.DefaultQuery("sort", models.NewsSortPublished),
+	}
+	if filter.Sort != models.NewsSortPublished && filter.Sort != models.NewsSortFetched {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "sort must be published or fetched"})
+		return
+	}
+
+	news, err := n.NewsService.GetNews(c.Request.Context(), userID.(int64), filter, page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
@@ -64,6 +75,68 @@ func (n *NewsHandler) GetNewsByID(c *gin.Context) {
 	c.JSON(http.StatusOK, news)
 }
 
+func (n *NewsHandler) AddBookmark(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "user not authorized"})
+		return
+	}
+	newsID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid news id"})
+		return
+	}
+
+	err = n.NewsService.AddBookmark(c.Request.Context(), userID.(int64), newsID)
+	if errors.Is(err, services.ErrNewsNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "bookmark added"})
+}
+
+func (n *NewsHandler) RemoveBookmark(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "user not authorized"})
+		return
+	}
+	newsID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid news id"})
+		return
+	}
+
+	if err := n.NewsService.RemoveBookmark(c.Request.Context(), userID.(int64), newsID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "bookmark removed"})
+}
+
+func (n *NewsHandler) GetBookmarks(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "user not authorized"})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+	news, err := n.NewsService.GetBookmarks(c.Request.Context(), userID.(int64), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, news)
+}
+
 func (n *NewsHandler) GetActiveSources(c *gin.Context) {
 	sources, err := n.SourceService.GetActiveSources(c.Request.Context())
 	if err != nil {
@@ -97,6 +170,18 @@ func (n *NewsHandler) GetCategories(c *gin.Context) {
 	c.JSON(http.StatusOK, categories)
 }
 
+func (n *NewsHandler) GetTags(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	tags, err := n.NewsService.GetTags(c.Request.Context(), c.Query("kind"), limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
 func (n *NewsHandler) AddSource(c *gin.Context) {
 	var req models.CreateSourceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -105,6 +190,22 @@ func (n *NewsHandler) AddSource(c *gin.Context) {
 	}
 
 	source, err := n.SourceService.CreateSource(c.Request.Context(), &req)
+	var choiceErr *services.FeedChoiceError
+	if errors.As(err, &choiceErr) {
+		c.JSON(http.StatusMultipleChoices, models.FeedChoiceResponse{
+			Error:      err.Error(),
+			Candidates: choiceErr.Candidates,
+		})
+		return
+	}
+	if errors.Is(err, services.ErrSourceAlreadyExists) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidFeed) || errors.Is(err, services.ErrFeedNotFound) {
+		c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 		return
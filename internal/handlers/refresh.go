@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
@@ -23,10 +24,14 @@ func (r *RefreshHandler) RequestRefresh(c *gin.Context) {
 		return
 	}
 	req, err := r.refreshService.RequestRefresh(c.Request.Context(), userID.(int64))
-	if err != nil {
+	if errors.Is(err, services.ErrRefreshTooSoon) || errors.Is(err, services.ErrRefreshQueueFull) {
 		c.JSON(http.StatusTooManyRequests, models.ErrorResponse{Error: err.Error()})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusAccepted, gin.H{
 		"request_id": req.ID,
@@ -36,10 +41,14 @@ func (r *RefreshHandler) RequestRefresh(c *gin.Context) {
 }
 
 func (r *RefreshHandler) GetRefreshStatus(c *gin.Context) {
-	requestID := c.Param("id")
-	if req, ok := r.refreshService.GetRequestStatus(requestID); ok {
-		c.JSON(http.StatusOK, req)
+	req, err := r.refreshService.GetRequestStatus(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if req == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "request not found"})
 		return
 	}
-	c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "request not found"})
+	c.JSON(http.StatusOK, req)
 }
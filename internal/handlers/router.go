@@ -20,6 +20,8 @@ func NewRouter(
 	sourceService *services.SourceService,
 	adminService *services.AdminService,
 	refreshService *services.RefreshService,
+	settingsService *services.SettingsService,
+	retentionService *services.RetentionService,
 	jwtManager *auth.JWTManager,
 	cfg *config.Config,
 ) *gin.Engine {
@@ -38,11 +40,11 @@ func NewRouter(
 	router.Use(cors.New(corsConfig))
 
 	authHandler := NewAuthHandler(authService)
-	userHandler := NewUserHandler(userService)
+	userHandler := NewUserHandler(userService, settingsService)
 	refreshHandler := NewRefreshHandler(refreshService)
 	newsHandler := NewNewsHandler(newsService, sourceService, categoryService)
 	subscriptionHandler := NewSubscriptionHandler(subscriptionService)
-	adminHandler := NewAdminHandler(adminService, sourceService, categoryService)
+	adminHandler := NewAdminHandler(adminService, sourceService, categoryService, retentionService)
 
 	authGroup := router.Group("/auth")
 	{
@@ -57,8 +59,11 @@ func NewRouter(
 		userGroup := protected.Group("/user")
 		{
 			userGroup.GET("/profile", userHandler.GetProfile)
+			userGroup.GET("/settings", userHandler.GetSettings)
+			userGroup.PUT("/settings", userHandler.UpdateSettings)
 			userGroup.POST("/refresh", refreshHandler.RequestRefresh)
 			userGroup.GET("/refresh/:id", refreshHandler.GetRefreshStatus)
+			userGroup.GET("/bookmarks", newsHandler.GetBookmarks)
 		}
 
 		subscriptionGroup := protected.Group("/user/subscriptions")
@@ -76,7 +81,10 @@ func NewRouter(
 			newsGroup.GET("/all-sources", newsHandler.GetAllSources)
 			newsGroup.POST("/sources", newsHandler.AddSource)
 			newsGroup.GET("/categories", newsHandler.GetCategories)
+			newsGroup.GET("/tags", newsHandler.GetTags)
 			newsGroup.GET("/source/:id", newsHandler.GetNewsBySource)
+			newsGroup.POST("/:id/bookmark", newsHandler.AddBookmark)
+			newsGroup.DELETE("/:id/bookmark", newsHandler.RemoveBookmark)
 		}
 
 		adminGroup := protected.Group("/admin")
@@ -86,9 +94,13 @@ func NewRouter(
 			adminGroup.POST("/users/:id/remove-admin", adminHandler.RemoveAdmin)
 
 			adminGroup.GET("/users", adminHandler.GetUsers)
+			adminGroup.GET("/sources/health", adminHandler.GetSourcesHealth)
 			adminGroup.PUT("/sources/:id", adminHandler.UpdateSource)
 			adminGroup.DELETE("/sources/:id", adminHandler.DeleteSource)
 			adminGroup.POST("/categories", adminHandler.AddCategory)
+			adminGroup.GET("/retention", adminHandler.GetRetention)
+			adminGroup.POST("/retention/run", adminHandler.RunRetention)
+			adminGroup.GET("/retention/runs/:id", adminHandler.GetRetentionRun)
 		}
 	}
 
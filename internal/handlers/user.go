@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
@@ -9,12 +10,14 @@ import (
 )
 
 type UserHandler struct {
-	UserService *services.UserService
+	UserService     *services.UserService
+	SettingsService *services.SettingsService
 }
 
-func NewUserHandler(userService *services.UserService) *UserHandler {
+func NewUserHandler(userService *services.UserService, settingsService *services.SettingsService) *UserHandler {
 	return &UserHandler{
-		UserService: userService,
+		UserService:     userService,
+		SettingsService: settingsService,
 	}
 }
 
@@ -33,3 +36,45 @@ func (u *UserHandler) GetProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+func (u *UserHandler) GetSettings(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "User not unauthorized"})
+		return
+	}
+
+	settings, err := u.SettingsService.GetSettings(c.Request.Context(), userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (u *UserHandler) UpdateSettings(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "User not unauthorized"})
+		return
+	}
+
+	var req models.UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	settings, err := u.SettingsService.UpdateSettings(c.Request.Context(), userID.(int64), &req)
+	if errors.Is(err, services.ErrInvalidSettings) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
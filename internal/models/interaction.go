@@ -37,10 +37,26 @@ type NewsResponse struct {
 	SourceID    int64     `json:"source_id"`
 	SourceName  string    `json:"source_name"`
 	CategoryID  *int64    `json:"category_id,omitempty"`
+
+	ContentText    *string      `json:"content_text,omitempty"`
+	WordCount      int          `json:"word_count"`
+	ReadingMinutes int          `json:"reading_time_minutes"`
+	Sources        []NewsSource `json:"sources,omitempty"`
+
+	ArticleHTML *string `json:"article_html,omitempty"`
+	ArticleText *string `json:"article_text,omitempty"`
+
+	Media []NewsMedia `json:"media,omitempty"`
+	Tags  []Tag       `json:"tags,omitempty"`
+
+	FetchedAt time.Time `json:"fetched_at"`
+	// Updated - новость исправлялась источником после публикации
+	Updated   bool       `json:"updated"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
 }
 
 type CreateSourceRequest struct {
-	Name       string `json:"name" binding:"required"`
+	Name       string `json:"name"`
 	URL        string `json:"url" binding:"required,url"`
 	CategoryID *int64 `json:"category_id,omitempty"`
 	IsActive   bool   `json:"is_active"`
@@ -51,6 +67,23 @@ type UpdateSourceRequest struct {
 	URL        *string `json:"url,omitempty" binding:"omitempty,url"`
 	CategoryID *int64  `json:"category_id,omitempty"`
 	IsActive   *bool   `json:"is_active,omitempty"`
+
+	MinFetchInterval *int `json:"min_fetch_interval,omitempty" binding:"omitempty,min=60"`
+	MaxFetchInterval *int `json:"max_fetch_interval,omitempty" binding:"omitempty,min=60"`
+
+	FetchFullText *bool `json:"fetch_full_text,omitempty"`
+	// Encoding - кодировка ленты; пустая строка сбрасывает ее к автоопределению
+	Encoding *string `json:"encoding,omitempty"`
+	// RetentionDays - срок хранения новостей в днях; -1 возвращает общий срок
+	RetentionDays *int `json:"retention_days,omitempty" binding:"omitempty,min=-1"`
+}
+
+type UpdateSettingsRequest struct {
+	DeliveryMode  *string `json:"delivery_mode,omitempty" binding:"omitempty,oneof=instant hourly daily weekly"`
+	DigestTime    *string `json:"digest_time,omitempty"`
+	DigestWeekday *int    `json:"digest_weekday,omitempty" binding:"omitempty,min=1,max=7"`
+	Timezone      *string `json:"timezone,omitempty"`
+	GroupBy       *string `json:"group_by,omitempty" binding:"omitempty,oneof=source category"`
 }
 
 type CreateCategoryRequest struct {
@@ -72,3 +105,43 @@ type PaginatedResponse[T any] struct {
 	PageSize   int   `json:"page_size"`
 	TotalPages int   `json:"total_pages"`
 }
+
+// FeedCandidate - лента, найденная по адресу сайта.
+type FeedCandidate struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+type FeedChoiceResponse struct {
+	Error      string          `json:"error"`
+	Candidates []FeedCandidate `json:"candidates"`
+}
+
+// FeedPreview - результат пробной загрузки ленты при добавлении источника.
+type FeedPreview struct {
+	Title       string            `json:"title"`
+	Description string            `json:"description,omitempty"`
+	ItemCount   int               `json:"item_count"`
+	Items       []FeedPreviewItem `json:"items"`
+}
+
+type FeedPreviewItem struct {
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// CreateSourceResponse - созданный источник вместе с превью его ленты.
+type CreateSourceResponse struct {
+	Source
+	Preview *FeedPreview `json:"preview,omitempty"`
+}
+
+// RetentionStatusResponse - политика хранения новостей и последние запуски очистки.
+type RetentionStatusResponse struct {
+	DefaultDays int            `json:"default_days"`
+	Mode        string         `json:"mode"`
+	Interval    int            `json:"interval_hours"`
+	Running     bool           `json:"running"`
+	Runs        []RetentionRun `json:"runs"`
+}
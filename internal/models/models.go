@@ -18,11 +18,38 @@ type Category struct {
 }
 
 type Source struct {
-	ID         int64  `json:"id" db:"id"`
-	Name       string `json:"name" db:"name"`
-	URL        string `json:"url" db:"url"`
-	CategoryID *int64 `json:"category_id,omitempty" db:"category_id"`
-	IsActive   bool   `json:"is_active" db:"is_active"`
+	ID           int64   `json:"id" db:"id"`
+	Name         string  `json:"name" db:"name"`
+	URL          string  `json:"url" db:"url"`
+	CanonicalURL string  `json:"-" db:"canonical_url"`
+	CategoryID   *int64  `json:"category_id,omitempty" db:"category_id"`
+	IsActive     bool    `json:"is_active" db:"is_active"`
+	ETag         *string `json:"-" db:"etag"`
+	LastModified *string `json:"-" db:"last_modified"`
+
+	FetchInterval    *int       `json:"fetch_interval,omitempty" db:"fetch_interval_seconds"`
+	MinFetchInterval int        `json:"min_fetch_interval" db:"min_fetch_interval_seconds"`
+	MaxFetchInterval int        `json:"max_fetch_interval" db:"max_fetch_interval_seconds"`
+	NextFetchAt      *time.Time `json:"next_fetch_at,omitempty" db:"next_fetch_at"`
+
+	FetchFullText bool `json:"fetch_full_text" db:"fetch_full_text"`
+	// Encoding задает кодировку ленты вместо объявленной в ответе
+	Encoding *string `json:"encoding,omitempty" db:"encoding"`
+	// RetentionDays - срок хранения новостей в днях вместо общего, 0 - хранить всегда
+	RetentionDays *int `json:"retention_days,omitempty" db:"retention_days"`
+}
+
+type SourceHealth struct {
+	SourceID            int64      `json:"source_id" db:"id"`
+	Name                string     `json:"name" db:"name"`
+	URL                 string     `json:"url" db:"url"`
+	IsActive            bool       `json:"is_active" db:"is_active"`
+	LastFetchedAt       *time.Time `json:"last_fetched_at,omitempty" db:"last_fetched_at"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty" db:"last_success_at"`
+	LastError           *string    `json:"last_error,omitempty" db:"last_error"`
+	ConsecutiveFailures int        `json:"consecutive_failures" db:"consecutive_failures"`
+	LastHTTPStatus      *int       `json:"last_http_status,omitempty" db:"last_http_status"`
+	AvgFetchLatencyMs   *int       `json:"avg_fetch_latency_ms,omitempty" db:"avg_fetch_latency_ms"`
 }
 
 type NewsItem struct {
@@ -33,9 +60,203 @@ type NewsItem struct {
 	PublishedAt time.Time `json:"published_at" db:"published_at"`
 	SourceID    int64     `json:"source_id" db:"source_id"`
 	GUID        string    `json:"guid" db:"guid"`
+
+	CanonicalURL   string       `json:"-" db:"canonical_url"`
+	ContentText    *string      `json:"content_text,omitempty" db:"content_text"`
+	WordCount      int          `json:"word_count" db:"word_count"`
+	ReadingMinutes int          `json:"reading_time_minutes" db:"reading_time_minutes"`
+	Fingerprint    *int64       `json:"-" db:"fingerprint"`
+	DuplicateOf    *int64       `json:"duplicate_of,omitempty" db:"duplicate_of"`
+	Sources        []NewsSource `json:"sources,omitempty" db:"-"`
+
+	ArticleHTML *string `json:"article_html,omitempty" db:"article_html"`
+	ArticleText *string `json:"article_text,omitempty" db:"article_text"`
+
+	Media []NewsMedia `json:"media,omitempty" db:"-"`
+	Tags  []Tag       `json:"tags,omitempty" db:"-"`
+
+	// FetchedAt - когда новость впервые попала в базу
+	FetchedAt time.Time `json:"fetched_at" db:"fetched_at"`
+
+	// ContentHash - хеш заголовка и текста из ленты, по нему замечаются правки
+	ContentHash   string     `json:"-" db:"content_hash"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	RevisionCount int        `json:"revision_count" db:"revision_count"`
+}
+
+// NewsEdit - правка уже сохраненной новости: прежние и новые заголовок и
+// отпечаток текста.
+type NewsEdit struct {
+	NewsID              int64
+	PreviousTitle       string
+	PreviousFingerprint *int64
+	Title               string
+	Fingerprint         *int64
+}
+
+const (
+	MediaKindImage = "image"
+	MediaKindAudio = "audio"
+	MediaKindVideo = "video"
+)
+
+// NewsMedia - вложение новости: картинка, аудио или видео из enclosure,
+// media:content или изображения элемента ленты.
+type NewsMedia struct {
+	ID         int64   `json:"id" db:"id"`
+	NewsItemID int64   `json:"news_item_id" db:"news_item_id"`
+	URL        string  `json:"url" db:"url"`
+	MimeType   *string `json:"mime_type,omitempty" db:"mime_type"`
+	Size       *int64  `json:"size,omitempty" db:"size_bytes"`
+	Kind       string  `json:"kind" db:"kind"`
+	Position   int     `json:"-" db:"position"`
+}
+
+// NewsSource - публикация одной и той же новости в конкретном источнике.
+type NewsSource struct {
+	NewsID     int64  `json:"news_id"`
+	SourceID   int64  `json:"source_id"`
+	SourceName string `json:"source_name"`
+	URL        string `json:"url"`
+}
+
+const (
+	TagKindCategory = "category"
+	TagKindAuthor   = "author"
+)
+
+// Tag - рубрика или автор новости из ленты. Slug - нормализованное имя, по
+// которому фильтруется лента.
+type Tag struct {
+	ID        int64  `json:"id" db:"id"`
+	Name      string `json:"name" db:"name"`
+	Slug      string `json:"slug" db:"slug"`
+	Kind      string `json:"kind" db:"kind"`
+	NewsCount int64  `json:"news_count,omitempty" db:"-"`
+}
+
+const (
+	NewsSortPublished = "published"
+	NewsSortFetched   = "fetched"
+)
+
+// NewsFilter - условия отбора ленты. Пустые поля не ограничивают выборку.
+// Sort задает порядок ленты: по дате публикации (по умолчанию) или по времени
+// получения новости.
+type NewsFilter struct {
+	Tag    string
+	Author string
+	Sort   string
 }
 
 type UserSource struct {
 	UserID   int64 `json:"user_id" db:"user_id"`
 	SourceID int64 `json:"source_id" db:"source_id"`
 }
+
+type NewsDelivery struct {
+	ID            int64      `json:"id" db:"id"`
+	UserID        int64      `json:"user_id" db:"user_id"`
+	NewsItemID    int64      `json:"news_item_id" db:"news_item_id"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	LastError     *string    `json:"last_error,omitempty" db:"last_error"`
+}
+
+type PendingDelivery struct {
+	DeliveryID   int64
+	Attempts     int
+	IsUpdate     bool
+	UserID       int64
+	ChatID       int64
+	News         NewsItem
+	SourceName   string
+	CategoryName string
+}
+
+const (
+	DeliveryModeInstant = "instant"
+	DeliveryModeHourly  = "hourly"
+	DeliveryModeDaily   = "daily"
+	DeliveryModeWeekly  = "weekly"
+
+	DigestGroupBySource   = "source"
+	DigestGroupByCategory = "category"
+)
+
+type UserSettings struct {
+	UserID        int64      `json:"user_id" db:"user_id"`
+	DeliveryMode  string     `json:"delivery_mode" db:"delivery_mode"`
+	DigestTime    string     `json:"digest_time" db:"digest_time"`
+	DigestWeekday int        `json:"digest_weekday" db:"digest_weekday"`
+	Timezone      string     `json:"timezone" db:"timezone"`
+	GroupBy       string     `json:"group_by" db:"group_by"`
+	LastDigestAt  *time.Time `json:"last_digest_at,omitempty" db:"last_digest_at"`
+	NextDigestAt  *time.Time `json:"next_digest_at,omitempty" db:"next_digest_at"`
+}
+
+type Digest struct {
+	ChatID int64
+	Since  *time.Time
+	Groups []DigestGroup
+}
+
+type DigestGroup struct {
+	Name string
+	News []NewsItem
+}
+
+const (
+	RetentionModeArchive = "archive"
+	RetentionModeDelete  = "delete"
+
+	RetentionTriggerSchedule = "schedule"
+	RetentionTriggerManual   = "manual"
+
+	RetentionStatusQueued    = "queued"
+	RetentionStatusRunning   = "running"
+	RetentionStatusCompleted = "completed"
+	RetentionStatusFailed    = "failed"
+)
+
+// RetentionRun - запуск очистки устаревших новостей.
+type RetentionRun struct {
+	ID            int64      `json:"id" db:"id"`
+	Trigger       string     `json:"trigger" db:"trigger"`
+	Mode          string     `json:"mode" db:"mode"`
+	Status        string     `json:"status" db:"status"`
+	RequestedAt   time.Time  `json:"requested_at" db:"requested_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+	RemovedCount  int64      `json:"removed_count" db:"removed_count"`
+	ArchivedCount int64      `json:"archived_count" db:"archived_count"`
+	Error         *string    `json:"error,omitempty" db:"error"`
+}
+
+const (
+	RefreshStatusQueued     = "queued"
+	RefreshStatusProcessing = "processing"
+	RefreshStatusCompleted  = "completed"
+	RefreshStatusFailed     = "failed"
+)
+
+// RefreshRequest - задача обновления новостей пользователя из очереди
+// refresh_jobs. Ее может поставить и выполнить любой процесс (API или бот).
+type RefreshRequest struct {
+	ID         string
+	UserID     int64
+	Timestamp  time.Time
+	Status     string
+	Result     int
+	Error      *string    `json:",omitempty"`
+	FinishedAt *time.Time `json:",omitempty"`
+}
+
+// StoredURL - сохраненная ссылка источника или новости и ее каноническая форма.
+type StoredURL struct {
+	ID           int64
+	URL          string
+	CanonicalURL string
+}
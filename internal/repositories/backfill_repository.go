@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type backfillRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewBackfillRepository(pool *pgxpool.Pool) BackfillRepository {
+	return &backfillRepository{pool: pool}
+}
+
+func (r *backfillRepository) IsDone(ctx context.Context, name string) (bool, error) {
+	var done bool
+	err := r.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM data_backfills WHERE name = $1)", name).Scan(&done)
+	if err != nil {
+		return false, fmt.Errorf("failed to check backfill %s: %w", name, err)
+	}
+	return done, nil
+}
+
+func (r *backfillRepository) MarkDone(ctx context.Context, name string) error {
+	_, err := r.pool.Exec(ctx, "INSERT INTO data_backfills (name) VALUES ($1) ON CONFLICT DO NOTHING", name)
+	if err != nil {
+		return fmt.Errorf("failed to mark backfill %s: %w", name, err)
+	}
+	return nil
+}
+
+func (r *backfillRepository) GetSourceURLs(ctx context.Context) ([]models.StoredURL, error) {
+	rows, err := r.pool.Query(ctx, "SELECT id, url, canonical_url FROM sources ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source urls: %w", err)
+	}
+	return scanStoredURLs(rows)
+}
+
+func (r *backfillRepository) GetNewsURLs(ctx context.Context, afterID int64, limit int) ([]models.StoredURL, error) {
+	query := `
+        SELECT id, url, canonical_url
+        FROM news_item_keys
+        WHERE id > $1
+        ORDER BY id
+        LIMIT $2
+    `
+	rows, err := r.pool.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get news urls: %w", err)
+	}
+	return scanStoredURLs(rows)
+}
+
+// UpdateSourceCanonicalURLs записывает новые канонические ссылки источников.
+// Ссылка, которая уже занята другим источником, не меняется.
+func (r *backfillRepository) UpdateSourceCanonicalURLs(ctx context.Context, urls []models.StoredURL) (int64, error) {
+	ids, canonicalURLs := splitStoredURLs(urls)
+	query := `
+        UPDATE sources s
+        SET canonical_url = t.canonical_url
+        FROM unnest($1::bigint[], $2::text[]) AS t(id, canonical_url)
+        WHERE s.id = t.id AND s.canonical_url <> t.canonical_url
+          AND LENGTH(t.canonical_url) <= 500
+          AND NOT EXISTS (SELECT 1 FROM sources o WHERE o.canonical_url = t.canonical_url)
+    `
+	res, err := r.pool.Exec(ctx, query, ids, canonicalURLs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update source canonical urls: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+// UpdateNewsCanonicalURLs записывает новые канонические ссылки новостей в
+// news_item_keys и news_items. Ссылка, которая уже занята другой новостью,
+// не меняется.
+func (r *backfillRepository) UpdateNewsCanonicalURLs(ctx context.Context, urls []models.StoredURL) (int64, error) {
+	ids, canonicalURLs := splitStoredURLs(urls)
+	query := `
+        WITH updated AS (
+            UPDATE news_item_keys k
+            SET canonical_url = t.canonical_url
+            FROM unnest($1::bigint[], $2::text[]) AS t(id, canonical_url)
+            WHERE k.id = t.id AND k.canonical_url <> t.canonical_url
+              AND LENGTH(t.canonical_url) <= 500
+              AND NOT EXISTS (SELECT 1 FROM news_item_keys o WHERE o.canonical_url = t.canonical_url)
+            RETURNING k.id, k.published_at, k.canonical_url
+        ),
+        items AS (
+            UPDATE news_items ni
+            SET canonical_url = u.canonical_url
+            FROM updated u
+            WHERE ni.id = u.id AND ni.published_at = u.published_at
+        )
+        SELECT COUNT(*) FROM updated
+    `
+	var updated int64
+	if err := r.pool.QueryRow(ctx, query, ids, canonicalURLs).Scan(&updated); err != nil {
+		return 0, fmt.Errorf("failed to update news canonical urls: %w", err)
+	}
+	return updated, nil
+}
+
+func splitStoredURLs(urls []models.StoredURL) ([]int64, []string) {
+	ids := make([]int64, 0, len(urls))
+	canonicalURLs := make([]string, 0, len(urls))
+	for _, u := range urls {
+		ids = append(ids, u.ID)
+		canonicalURLs = append(canonicalURLs, u.CanonicalURL)
+	}
+	return ids, canonicalURLs
+}
+
+func scanStoredURLs(rows pgx.Rows) ([]models.StoredURL, error) {
+	defer rows.Close()
+
+	var urls []models.StoredURL
+	for rows.Next() {
+		var u models.StoredURL
+		if err := rows.Scan(&u.ID, &u.URL, &u.CanonicalURL); err != nil {
+			return nil, fmt.Errorf("failed to scan url: %w", err)
+		}
+		urls = append(urls, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return urls, nil
+}
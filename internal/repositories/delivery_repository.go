@@ -0,0 +1,285 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type deliveryRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewDeliveryRepository(pool *pgxpool.Pool) DeliveryRepository {
+	return &deliveryRepository{pool: pool}
+}
+
+func (r *deliveryRepository) EnqueueForNews(ctx context.Context, newsIDs []int64) (int64, error) {
+	if len(newsIDs) == 0 {
+		return 0, nil
+	}
+
+	query := `
+        INSERT INTO news_deliveries (user_id, news_item_id)
+        SELECT DISTINCT us.user_id, ni.id
+        FROM news_item_keys ni
+        JOIN news_item_sources ms ON ms.news_item_id = ni.id
+        JOIN user_sources us ON us.source_id = ms.source_id
+        JOIN users u ON u.id = us.user_id
+        CROSS JOIN (
+            SELECT MIN(published_at) AS min_published_at, MAX(published_at) AS max_published_at
+            FROM news_item_keys WHERE id = ANY($1)
+        ) b
+        WHERE ni.id = ANY($1) AND u.tg_chat_id IS NOT NULL
+          AND EXISTS (
+              SELECT 1 FROM news_items n
+              WHERE n.id = ni.id AND n.published_at = ni.published_at
+                AND n.published_at BETWEEN b.min_published_at AND b.max_published_at
+          )
+          AND NOT EXISTS (
+              SELECT 1
+              FROM news_items dup
+              JOIN news_item_sources oms ON oms.news_item_id = dup.duplicate_of
+              JOIN user_sources ous ON ous.source_id = oms.source_id AND ous.user_id = us.user_id
+              WHERE dup.id = ni.id AND dup.published_at = ni.published_at
+                AND dup.published_at BETWEEN b.min_published_at AND b.max_published_at
+          )
+        ON CONFLICT (user_id, news_item_id) DO NOTHING
+    `
+
+	res, err := r.pool.Exec(ctx, query, newsIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue deliveries: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+// RequeueUpdated повторно ставит в очередь уже отправленные новости, чтобы
+// подписчики получили их исправленную версию.
+func (r *deliveryRepository) RequeueUpdated(ctx context.Context, newsIDs []int64) (int64, error) {
+	if len(newsIDs) == 0 {
+		return 0, nil
+	}
+
+	query := `
+        UPDATE news_deliveries
+        SET status = 'pending', is_update = true, attempts = 0, next_attempt_at = NOW(),
+            claimed_at = NULL, sent_at = NULL, last_error = NULL
+        WHERE news_item_id = ANY($1) AND status = 'sent'
+    `
+
+	res, err := r.pool.Exec(ctx, query, newsIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue updated deliveries: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+// ClaimPending забирает новости к отправке. Доставки в статусе processing
+// дольше staleAfter считаются брошенными упавшим процессом и забираются
+// снова: отправлено ли сообщение, неизвестно, поэтому доставка в этом случае
+// выполняется как минимум один раз, а не ровно один.
+func (r *deliveryRepository) ClaimPending(ctx context.Context, limit int, staleAfter time.Duration) ([]models.PendingDelivery, error) {
+	query := `
+        WITH claimed AS (
+            UPDATE news_deliveries
+            SET status = 'processing', attempts = attempts + 1, claimed_at = NOW()
+            WHERE id IN (
+                SELECT id FROM news_deliveries
+                WHERE ((status = 'pending' AND next_attempt_at <= NOW())
+                   OR (status = 'processing' AND claimed_at < NOW() - make_interval(secs => $2)))
+                  AND NOT EXISTS (
+                      SELECT 1 FROM user_settings st
+                      WHERE st.user_id = news_deliveries.user_id AND st.delivery_mode <> 'instant'
+                  )
+                ORDER BY next_attempt_at
+                LIMIT $1
+                FOR UPDATE SKIP LOCKED
+            )
+            RETURNING id, user_id, news_item_id, attempts, is_update
+        )
+        SELECT %s
+        FROM claimed c
+        %s
+        ORDER BY ni.published_at
+    `
+
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, deliveryColumns, deliveryJoins), limit, staleAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	return scanPendingDeliveries(rows)
+}
+
+const deliveryColumns = `c.id, c.attempts, c.is_update, c.user_id, u.tg_chat_id,
+               ni.id, ni.title, ni.content, ni.url, ni.published_at, ni.source_id, ni.guid,
+               ni.content_text, ni.reading_time_minutes,
+               nm.url, nm.mime_type, nm.size_bytes, nm.kind,
+               s.name, COALESCE(cat.name, '')`
+
+const deliveryJoins = `JOIN users u ON u.id = c.user_id
+        JOIN news_item_keys k ON k.id = c.news_item_id
+        JOIN news_items ni ON ni.id = k.id AND ni.published_at = k.published_at
+            AND ni.published_at BETWEEN (
+                SELECT MIN(ck.published_at) FROM claimed cc JOIN news_item_keys ck ON ck.id = cc.news_item_id
+            ) AND (
+                SELECT MAX(ck.published_at) FROM claimed cc JOIN news_item_keys ck ON ck.id = cc.news_item_id
+            )
+        JOIN sources s ON s.id = ni.source_id
+        LEFT JOIN categories cat ON cat.id = s.category_id
+        LEFT JOIN LATERAL (
+            SELECT m.url, m.mime_type, m.size_bytes, m.kind
+            FROM news_media m
+            WHERE m.news_item_id = ni.id AND m.kind IN ('image', 'audio')
+            ORDER BY m.kind = 'audio' DESC, m.position
+            LIMIT 1
+        ) nm ON true`
+
+func scanPendingDeliveries(rows pgx.Rows) ([]models.PendingDelivery, error) {
+	defer rows.Close()
+
+	var deliveries []models.PendingDelivery
+	for rows.Next() {
+		var d models.PendingDelivery
+		var mediaURL, mediaKind *string
+		var media models.NewsMedia
+		if err := rows.Scan(
+			&d.DeliveryID,
+			&d.Attempts,
+			&d.IsUpdate,
+			&d.UserID,
+			&d.ChatID,
+			&d.News.ID,
+			&d.News.Title,
+			&d.News.Content,
+			&d.News.URL,
+			&d.News.PublishedAt,
+			&d.News.SourceID,
+			&d.News.GUID,
+			&d.News.ContentText,
+			&d.News.ReadingMinutes,
+			&mediaURL,
+			&media.MimeType,
+			&media.Size,
+			&mediaKind,
+			&d.SourceName,
+			&d.CategoryName,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		if mediaURL != nil && mediaKind != nil {
+			media.NewsItemID = d.News.ID
+			media.URL = *mediaURL
+			media.Kind = *mediaKind
+			d.News.Media = []models.NewsMedia{media}
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *deliveryRepository) MarkSent(ctx context.Context, deliveryID int64) error {
+	query := `
+        UPDATE news_deliveries
+        SET status = 'sent', sent_at = NOW(), claimed_at = NULL, last_error = NULL
+        WHERE id = $1
+    `
+	_, err := r.pool.Exec(ctx, query, deliveryID)
+	return err
+}
+
+func (r *deliveryRepository) MarkRetry(ctx context.Context, deliveryID int64, nextAttemptAt time.Time, lastError string) error {
+	query := `
+        UPDATE news_deliveries
+        SET status = 'pending', next_attempt_at = $2, claimed_at = NULL, last_error = $3
+        WHERE id = $1
+    `
+	_, err := r.pool.Exec(ctx, query, deliveryID, nextAttemptAt, lastError)
+	return err
+}
+
+func (r *deliveryRepository) MarkFailed(ctx context.Context, deliveryID int64, lastError string) error {
+	query := `
+        UPDATE news_deliveries
+        SET status = 'failed', claimed_at = NULL, last_error = $2
+        WHERE id = $1
+    `
+	_, err := r.pool.Exec(ctx, query, deliveryID, lastError)
+	return err
+}
+
+// ClaimPendingForUser забирает новости для дайджеста пользователя. Доставки,
+// исчерпавшие maxAttempts попыток, не забираются: брошенные упавшим процессом
+// такие доставки сразу помечаются failed.
+func (r *deliveryRepository) ClaimPendingForUser(ctx context.Context, userID int64, limit int, maxAttempts int) ([]models.PendingDelivery, error) {
+	query := `
+        WITH exhausted AS (
+            UPDATE news_deliveries
+            SET status = 'failed', claimed_at = NULL,
+                last_error = COALESCE(last_error, 'delivery attempts exhausted')
+            WHERE user_id = $1 AND status = 'processing'
+              AND claimed_at < NOW() - INTERVAL '5 minutes' AND attempts >= $3
+        ), claimed AS (
+            UPDATE news_deliveries
+            SET status = 'processing', attempts = attempts + 1, claimed_at = NOW()
+            WHERE id IN (
+                SELECT id FROM news_deliveries
+                WHERE user_id = $1 AND attempts < $3
+                  AND (status = 'pending'
+                   OR (status = 'processing' AND claimed_at < NOW() - INTERVAL '5 minutes'))
+                ORDER BY created_at
+                LIMIT $2
+                FOR UPDATE SKIP LOCKED
+            )
+            RETURNING id, user_id, news_item_id, attempts, is_update
+        )
+        SELECT %s
+        FROM claimed c
+        %s
+        ORDER BY ni.published_at DESC
+    `
+
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, deliveryColumns, deliveryJoins), userID, limit, maxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim user deliveries: %w", err)
+	}
+	return scanPendingDeliveries(rows)
+}
+
+func (r *deliveryRepository) MarkSentMany(ctx context.Context, deliveryIDs []int64) error {
+	query := `
+        UPDATE news_deliveries
+        SET status = 'sent', sent_at = NOW(), claimed_at = NULL, last_error = NULL
+        WHERE id = ANY($1)
+    `
+	_, err := r.pool.Exec(ctx, query, deliveryIDs)
+	return err
+}
+
+func (r *deliveryRepository) ReleaseMany(ctx context.Context, deliveryIDs []int64, lastError string) error {
+	query := `
+        UPDATE news_deliveries
+        SET status = 'pending', claimed_at = NULL, last_error = $2
+        WHERE id = ANY($1)
+    `
+	_, err := r.pool.Exec(ctx, query, deliveryIDs, lastError)
+	return err
+}
+
+func (r *deliveryRepository) MarkFailedMany(ctx context.Context, deliveryIDs []int64, lastError string) error {
+	query := `
+        UPDATE news_deliveries
+        SET status = 'failed', claimed_at = NULL, last_error = $2
+        WHERE id = ANY($1)
+    `
+	_, err := r.pool.Exec(ctx, query, deliveryIDs, lastError)
+	return err
+}
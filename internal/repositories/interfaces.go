@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
 )
@@ -24,16 +25,30 @@ type SubscriptionRepository interface {
 }
 
 type NewsRepository interface {
-	GetNewsForUser(ctx context.Context, userID int64, page, pageSize int) ([]models.NewsItem, int64, error)
+	GetNewsForUser(ctx context.Context, userID int64, filter models.NewsFilter, page, pageSize int) ([]models.NewsItem, int64, error)
 	GetByID(ctx context.Context, id int) (*models.NewsItem, error)
 	GetBySourceWithPagination(ctx context.Context, sourceID int64, offset, limit int) ([]models.NewsItem, int64, error)
 	ExistsByGUID(ctx context.Context, sourceID int, guid string) (bool, error)
 	Create(ctx context.Context, news *models.NewsItem) error
+	CreateBatch(ctx context.Context, news []models.NewsItem) ([]int64, int, error)
+	LinkSources(ctx context.Context, news []models.NewsItem) ([]int64, error)
+	AdoptSyntheticGUIDs(ctx context.Context, news []models.NewsItem) (int64, error)
+	MarkDuplicates(ctx context.Context, newsIDs []int64, maxDistance int, window time.Duration) (int64, error)
+	CreateMedia(ctx context.Context, news []models.NewsItem) error
+	CreateTags(ctx context.Context, news []models.NewsItem) error
+	GetTags(ctx context.Context, kind string, limit int) ([]models.Tag, error)
+	UpdateArticle(ctx context.Context, newsID int64, articleHTML, articleText string, wordCount, readingMinutes int) error
+	UpdateRevisions(ctx context.Context, news []models.NewsItem) ([]models.NewsEdit, error)
+	AddBookmark(ctx context.Context, userID, newsID int64) error
+	RemoveBookmark(ctx context.Context, userID, newsID int64) error
+	GetBookmarks(ctx context.Context, userID int64, page, pageSize int) ([]models.NewsItem, int64, error)
 	Count(ctx context.Context) (int64, error)
+	CreatePartitions(ctx context.Context, from, until time.Time) (int, error)
 }
 
 type SourceRepository interface {
 	GetActive(ctx context.Context) ([]models.Source, error)
+	GetDue(ctx context.Context, now time.Time) ([]models.Source, error)
 	GetByID(ctx context.Context, id int) (*models.Source, error)
 	GetByURL(ctx context.Context, url string) (*models.Source, error)
 	Create(ctx context.Context, source *models.Source) error
@@ -41,9 +56,67 @@ type SourceRepository interface {
 	Delete(ctx context.Context, id int) error
 	GetActiveForUser(ctx context.Context, userID int64) ([]models.Source, error)
 	GetAllWithPagination(ctx context.Context, page, pageSize int) ([]models.Source, int64, error)
+	UpdateFetchCache(ctx context.Context, sourceID int64, etag, lastModified string) error
+	RecordFetchSuccess(ctx context.Context, sourceID int64, statusCode int, latency time.Duration) error
+	RecordFetchFailure(ctx context.Context, sourceID int64, statusCode int, lastError string, maxFailures int) (bool, error)
+	UpdateURL(ctx context.Context, sourceID int64, url, canonicalURL string) error
+	GetHealth(ctx context.Context, onlyFailing bool, page, pageSize int) ([]models.SourceHealth, int64, error)
+	ScheduleNextFetch(ctx context.Context, sourceID int64, interval time.Duration, nextFetchAt time.Time) error
 }
 
 type CategoryRepository interface {
 	GetAll(ctx context.Context) ([]models.Category, error)
 	Create(ctx context.Context, category *models.Category) error
 }
+
+type DeliveryRepository interface {
+	EnqueueForNews(ctx context.Context, newsIDs []int64) (int64, error)
+	RequeueUpdated(ctx context.Context, newsIDs []int64) (int64, error)
+	ClaimPending(ctx context.Context, limit int, staleAfter time.Duration) ([]models.PendingDelivery, error)
+	MarkSent(ctx context.Context, deliveryID int64) error
+	MarkRetry(ctx context.Context, deliveryID int64, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(ctx context.Context, deliveryID int64, lastError string) error
+	ClaimPendingForUser(ctx context.Context, userID int64, limit int, maxAttempts int) ([]models.PendingDelivery, error)
+	MarkSentMany(ctx context.Context, deliveryIDs []int64) error
+	ReleaseMany(ctx context.Context, deliveryIDs []int64, lastError string) error
+	MarkFailedMany(ctx context.Context, deliveryIDs []int64, lastError string) error
+}
+
+type SettingsRepository interface {
+	GetByUserID(ctx context.Context, userID int64) (*models.UserSettings, error)
+	Upsert(ctx context.Context, settings *models.UserSettings) error
+	GetDueDigests(ctx context.Context, now time.Time) ([]models.UserSettings, error)
+	ScheduleNextDigest(ctx context.Context, userID int64, sentAt *time.Time, nextDigestAt *time.Time) error
+}
+
+type RetentionRepository interface {
+	PurgeExpired(ctx context.Context, defaultDays, batchSize int, archive bool) (int64, int64, error)
+	PurgeExpiredKeys(ctx context.Context, defaultDays, graceDays, batchSize int) (int64, error)
+	CreateRun(ctx context.Context, run *models.RetentionRun) error
+	EnqueueRun(ctx context.Context, mode string) (*models.RetentionRun, error)
+	ClaimQueuedRun(ctx context.Context) (*models.RetentionRun, error)
+	FailInterruptedRuns(ctx context.Context) (int64, error)
+	FinishRun(ctx context.Context, run *models.RetentionRun) error
+	GetRun(ctx context.Context, id int64) (*models.RetentionRun, error)
+	GetRuns(ctx context.Context, limit int) ([]models.RetentionRun, error)
+}
+
+type RefreshRepository interface {
+	CountQueued(ctx context.Context) (int, error)
+	Enqueue(ctx context.Context, id string, userID int64, minGap time.Duration) (*models.RefreshRequest, error)
+	Claim(ctx context.Context, staleAfter time.Duration, maxAttempts int) (*models.RefreshRequest, error)
+	Complete(ctx context.Context, id string, result int) error
+	Fail(ctx context.Context, id string, lastError string) error
+	GetByID(ctx context.Context, id string) (*models.RefreshRequest, error)
+	FailStale(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int64, error)
+	DeleteFinished(ctx context.Context, olderThan time.Duration) (int64, error)
+}
+
+type BackfillRepository interface {
+	IsDone(ctx context.Context, name string) (bool, error)
+	MarkDone(ctx context.Context, name string) error
+	GetSourceURLs(ctx context.Context) ([]models.StoredURL, error)
+	GetNewsURLs(ctx context.Context, afterID int64, limit int) ([]models.StoredURL, error)
+	UpdateSourceCanonicalURLs(ctx context.Context, urls []models.StoredURL) (int64, error)
+	UpdateNewsCanonicalURLs(ctx context.Context, urls []models.StoredURL) (int64, error)
+}
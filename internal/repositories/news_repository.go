@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
 	"github.com/jackc/pgx/v5"
@@ -23,9 +24,11 @@ func NewNewsRepository(pool *pgxpool.Pool) NewsRepository {
 func (r *newsRepository) GetByID(ctx context.Context, id int) (*models.NewsItem, error) {
 	var news models.NewsItem
 	query := `
-        SELECT id, title, content, url, published_at, source_id, guid
-        FROM news_items 
-        WHERE id = $1
+        SELECT id, title, content, url, published_at, source_id, guid,
+               content_text, word_count, reading_time_minutes, article_html, article_text, fetched_at,
+               updated_at, revision_count
+        FROM news_items
+        WHERE id = $1 AND published_at = (SELECT published_at FROM news_item_keys WHERE id = $1)
     `
 
 	err := r.pool.QueryRow(ctx, query, id).Scan(
@@ -36,6 +39,14 @@ func (r *newsRepository) GetByID(ctx context.Context, id int) (*models.NewsItem,
 		&news.PublishedAt,
 		&news.SourceID,
 		&news.GUID,
+		&news.ContentText,
+		&news.WordCount,
+		&news.ReadingMinutes,
+		&news.ArticleHTML,
+		&news.ArticleText,
+		&news.FetchedAt,
+		&news.UpdatedAt,
+		&news.RevisionCount,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -45,20 +56,52 @@ func (r *newsRepository) GetByID(ctx context.Context, id int) (*models.NewsItem,
 		return nil, err
 	}
 
-	return &news, nil
+	items := []models.NewsItem{news}
+	if err := r.attachMedia(ctx, items); err != nil {
+		return nil, err
+	}
+	if err := r.attachTags(ctx, items); err != nil {
+		return nil, err
+	}
+
+	return &items[0], nil
 }
 
-func (r *newsRepository) GetNewsForUser(ctx context.Context, userID int64, page, pageSize int) ([]models.NewsItem, int64, error) {
+// newsFilterCondition ограничивает выборку по рубрике ($2) и автору ($3),
+// пустое значение фильтр отключает.
+const newsFilterCondition = `
+          AND ($2 = '' OR EXISTS (
+              SELECT 1 FROM news_item_tags nt JOIN tags t ON t.id = nt.tag_id
+              WHERE nt.news_item_id = ni.id AND t.kind = 'category' AND t.slug = $2))
+          AND ($3 = '' OR EXISTS (
+              SELECT 1 FROM news_item_tags nt JOIN tags t ON t.id = nt.tag_id
+              WHERE nt.news_item_id = ni.id AND t.kind = 'author' AND t.slug = $3))`
+
+// newsSortColumn возвращает колонку сортировки ленты для filter.Sort.
+func newsSortColumn(sort string) string {
+	if sort == models.NewsSortFetched {
+		return "fetched_at"
+	}
+	return "published_at"
+}
+
+// GetNewsForUser возвращает ленту пользователя, в которой копии одной новости
+// из разных источников свернуты в одну запись. Основной считается исходная
+// публикация, если пользователь на нее подписан, иначе самая ранняя копия.
+// Статья попадает в ленту через любой источник из news_item_sources, на
+// который подписан пользователь, и перечисляет все такие источники.
+func (r *newsRepository) GetNewsForUser(ctx context.Context, userID int64, filter models.NewsFilter, page, pageSize int) ([]models.NewsItem, int64, error) {
 	countQuery := `
-        SELECT COUNT(*) 
-        FROM news_items ni
-        JOIN user_sources us ON ni.source_id = us.source_id
-		JOIN sources s ON ni.source_id = s.id
-        WHERE us.user_id = $1 AND s.is_active = true
-    `
+        SELECT COUNT(DISTINCT COALESCE(ni.duplicate_of, ni.id))
+        FROM news_item_sources ms
+        JOIN user_sources us ON ms.source_id = us.source_id
+        JOIN sources s ON ms.source_id = s.id
+        JOIN news_item_keys k ON k.id = ms.news_item_id
+        JOIN news_items ni ON ni.id = k.id AND ni.published_at = k.published_at
+        WHERE us.user_id = $1 AND s.is_active = true` + newsFilterCondition
 
 	var total int64
-	err := r.pool.QueryRow(ctx, countQuery, userID).Scan(&total)
+	err := r.pool.QueryRow(ctx, countQuery, userID, filter.Tag, filter.Author).Scan(&total)
 	log.Println("NewsRepository GetNewsForUser")
 	log.Println(total)
 	if err != nil {
@@ -66,18 +109,43 @@ func (r *newsRepository) GetNewsForUser(ctx context.Context, userID int64, page,
 	}
 
 	query := `
-        SELECT ni.id, ni.title, ni.content, ni.url, ni.published_at, ni.source_id, ni.guid
-        FROM news_items ni
-        JOIN user_sources us ON ni.source_id = us.source_id
-		JOIN sources s ON ni.source_id = s.id
-        WHERE us.user_id = $1 AND s.is_active = true
-        ORDER BY ni.published_at DESC
-        LIMIT $2 OFFSET $3
+        WITH visible AS (
+            SELECT ni.id, ni.title, ni.content, ni.url, ni.published_at, ni.source_id, ni.guid,
+                   ni.content_text, ni.word_count, ni.reading_time_minutes, ni.duplicate_of, COALESCE(ni.duplicate_of, ni.id) AS cluster_id,
+                   ms.source_id AS via_source_id, s.name AS source_name, ms.url AS source_url,
+                   ni.fetched_at, ni.updated_at, ni.revision_count
+            FROM news_item_sources ms
+            JOIN user_sources us ON ms.source_id = us.source_id
+            JOIN sources s ON ms.source_id = s.id
+            JOIN news_item_keys k ON k.id = ms.news_item_id
+            JOIN news_items ni ON ni.id = k.id AND ni.published_at = k.published_at
+            WHERE us.user_id = $1 AND s.is_active = true` + newsFilterCondition + `
+        ),
+        leaders AS (
+            SELECT DISTINCT ON (cluster_id) *
+            FROM visible
+            ORDER BY cluster_id, duplicate_of IS NOT NULL, published_at, id
+        )
+        SELECT l.id, l.title, l.content, l.url, l.published_at, l.source_id, l.guid,
+               l.content_text, l.word_count, l.reading_time_minutes, l.duplicate_of, l.fetched_at,
+               l.updated_at, l.revision_count,
+               c.news_ids, c.source_ids, c.source_names, c.urls
+        FROM leaders l
+        JOIN LATERAL (
+            SELECT array_agg(v.id::bigint ORDER BY v.published_at, v.id, v.via_source_id) AS news_ids,
+                   array_agg(v.via_source_id::bigint ORDER BY v.published_at, v.id, v.via_source_id) AS source_ids,
+                   array_agg(v.source_name::text ORDER BY v.published_at, v.id, v.via_source_id) AS source_names,
+                   array_agg(v.source_url::text ORDER BY v.published_at, v.id, v.via_source_id) AS urls
+            FROM visible v
+            WHERE v.cluster_id = l.cluster_id
+        ) c ON true
+        ORDER BY l.` + newsSortColumn(filter.Sort) + ` DESC, l.id DESC
+        LIMIT $4 OFFSET $5
     `
 
 	offset := (page - 1) * pageSize
 	log.Print(page, pageSize)
-	rows, err := r.pool.Query(ctx, query, userID, pageSize, offset)
+	rows, err := r.pool.Query(ctx, query, userID, filter.Tag, filter.Author, pageSize, offset)
 	log.Printf("Rows: %v; Err: %v", rows, err)
 	if err != nil {
 		return nil, 0, err
@@ -87,6 +155,8 @@ func (r *newsRepository) GetNewsForUser(ctx context.Context, userID int64, page,
 	var news []models.NewsItem
 	for rows.Next() {
 		var item models.NewsItem
+		var newsIDs, sourceIDs []int64
+		var sourceNames, urls []string
 		err := rows.Scan(
 			&item.ID,
 			&item.Title,
@@ -95,26 +165,68 @@ func (r *newsRepository) GetNewsForUser(ctx context.Context, userID int64, page,
 			&item.PublishedAt,
 			&item.SourceID,
 			&item.GUID,
+			&item.ContentText,
+			&item.WordCount,
+			&item.ReadingMinutes,
+			&item.DuplicateOf,
+			&item.FetchedAt,
+			&item.UpdatedAt,
+			&item.RevisionCount,
+			&newsIDs,
+			&sourceIDs,
+			&sourceNames,
+			&urls,
 		)
-		// log.Printf("Item: %v\n", item)
 		if err != nil {
 			return nil, 0, err
 		}
+		for i := range newsIDs {
+			item.Sources = append(item.Sources, models.NewsSource{
+				NewsID:     newsIDs[i],
+				SourceID:   sourceIDs[i],
+				SourceName: sourceNames[i],
+				URL:        urls[i],
+			})
+		}
 		news = append(news, item)
 	}
-	log.Println(news)
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating rows: %w", err)
+	}
+	if err := r.attachMedia(ctx, news); err != nil {
+		return nil, 0, err
+	}
+	if err := r.attachTags(ctx, news); err != nil {
+		return nil, 0, err
+	}
 
 	return news, total, nil
 }
 
 func (r *newsRepository) Create(ctx context.Context, news *models.NewsItem) error {
 	query := `
-        INSERT INTO news_items 
-        (title, content, url, published_at, source_id, guid)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        WITH key AS (
+            INSERT INTO news_item_keys (source_id, guid, url, canonical_url, published_at)
+            VALUES ($5, $6, $3, COALESCE(NULLIF($7, ''), $3), $4)
+            RETURNING id, canonical_url
+        ),
+        mapped AS (
+            INSERT INTO news_item_sources (news_item_id, source_id, guid, url)
+            SELECT id, $5, $6, $3 FROM key
+        )
+        INSERT INTO news_items
+        (id, title, content, url, published_at, source_id, guid, canonical_url,
+         content_text, word_count, reading_time_minutes, fetched_at)
+        SELECT id, $1::text, $2::text, $3::text, $4::timestamptz, $5::int, $6::text, canonical_url,
+               $8::text, $9::int, $10::int, $11::timestamptz
+        FROM key
         RETURNING id
     `
 
+	if news.FetchedAt.IsZero() {
+		news.FetchedAt = time.Now()
+	}
+
 	return r.pool.QueryRow(ctx, query,
 		news.Title,
 		news.Content,
@@ -122,13 +234,647 @@ func (r *newsRepository) Create(ctx context.Context, news *models.NewsItem) erro
 		news.PublishedAt,
 		news.SourceID,
 		news.GUID,
+		news.CanonicalURL,
+		news.ContentText,
+		news.WordCount,
+		news.ReadingMinutes,
+		news.FetchedAt,
 	).Scan(&news.ID)
 }
 
+// CreateBatch вставляет новости одним запросом в транзакции. Новости, которые
+// уже есть в базе (по guid источника или по url), пропускаются: ключи
+// дедупликации проверяются в news_item_keys, общей для всех секций
+// news_items, и в news_item_sources. Вставленным новостям в news проставляется
+// ID по их номеру в пачке, а источник записывается в news_item_sources.
+// Ссылки и guid длиннее 500 символов должны быть отброшены заранее.
+func (r *newsRepository) CreateBatch(ctx context.Context, news []models.NewsItem) ([]int64, int, error) {
+	if len(news) == 0 {
+		return nil, 0, nil
+	}
+
+	titles := make([]string, len(news))
+	contents := make([]*string, len(news))
+	urls := make([]string, len(news))
+	canonicalURLs := make([]string, len(news))
+	publishedAt := make([]time.Time, len(news))
+	sourceIDs := make([]int64, len(news))
+	guids := make([]string, len(news))
+	fingerprints := make([]*int64, len(news))
+	contentTexts := make([]*string, len(news))
+	wordCounts := make([]int, len(news))
+	readingMinutes := make([]int, len(news))
+	fetchedAt := make([]time.Time, len(news))
+	contentHashes := make([]*string, len(news))
+	for i, item := range news {
+		titles[i] = item.Title
+		contents[i] = item.Content
+		urls[i] = item.URL
+		canonicalURLs[i] = item.CanonicalURL
+		if canonicalURLs[i] == "" {
+			canonicalURLs[i] = item.URL
+		}
+		publishedAt[i] = item.PublishedAt
+		sourceIDs[i] = item.SourceID
+		guids[i] = item.GUID
+		fingerprints[i] = item.Fingerprint
+		contentTexts[i] = item.ContentText
+		wordCounts[i] = item.WordCount
+		readingMinutes[i] = item.ReadingMinutes
+		fetchedAt[i] = item.FetchedAt
+		if fetchedAt[i].IsZero() {
+			fetchedAt[i] = time.Now()
+		}
+		if item.ContentHash != "" {
+			contentHashes[i] = &news[i].ContentHash
+		}
+	}
+
+	query := `
+        WITH input AS (
+            SELECT DISTINCT ON (t.source_id, t.guid) *
+            FROM unnest($1::text[], $2::text[], $3::text[], $4::timestamptz[], $5::bigint[], $6::text[], $7::bigint[], $8::text[],
+                        $9::text[], $10::int[], $11::int[], $12::timestamptz[], $13::text[]) WITH ORDINALITY
+                AS t(title, content, url, published_at, source_id, guid, fingerprint, canonical_url,
+                     content_text, word_count, reading_time_minutes, fetched_at, content_hash, ord)
+            ORDER BY t.source_id, t.guid, t.ord
+        ),
+        keys AS (
+            INSERT INTO news_item_keys (source_id, guid, url, canonical_url, published_at)
+            SELECT source_id, guid, url, canonical_url, published_at
+            FROM input i
+            WHERE NOT EXISTS (
+                SELECT 1 FROM news_item_sources ms WHERE ms.source_id = i.source_id AND ms.guid = i.guid
+            )
+            ON CONFLICT DO NOTHING
+            RETURNING id, source_id, guid
+        ),
+        mapped AS (
+            INSERT INTO news_item_sources (news_item_id, source_id, guid, url, content_hash)
+            SELECT k.id, t.source_id, t.guid, t.url, t.content_hash
+            FROM keys k
+            JOIN input t ON t.source_id = k.source_id AND t.guid = k.guid
+        )
+        items AS (
+            INSERT INTO news_items
+            (id, title, content, url, published_at, source_id, guid, fingerprint, canonical_url,
+             content_text, word_count, reading_time_minutes, fetched_at, content_hash)
+            SELECT k.id, LEFT(t.title, 500), t.content, t.url, t.published_at, t.source_id, t.guid, t.fingerprint, t.canonical_url,
+                   t.content_text, t.word_count, t.reading_time_minutes, t.fetched_at, t.content_hash
+            FROM keys k
+            JOIN input t ON t.source_id = k.source_id AND t.guid = k.guid
+        )
+        SELECT k.id, t.ord
+        FROM keys k
+        JOIN input t ON t.source_id = k.source_id AND t.guid = k.guid
+        ORDER BY t.ord
+    `
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, titles, contents, urls, publishedAt, sourceIDs, guids, fingerprints, canonicalURLs,
+		contentTexts, wordCounts, readingMinutes, fetchedAt, contentHashes)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to insert news batch: %w", err)
+	}
+	var inserted []insertedNews
+	for rows.Next() {
+		var row insertedNews
+		if err := rows.Scan(&row.id, &row.ordinal); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("failed to scan inserted news id: %w", err)
+		}
+		inserted = append(inserted, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to insert news batch: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, 0, fmt.Errorf("failed to commit news batch: %w", err)
+	}
+
+	ids := assignInsertedIDs(news, inserted)
+	return ids, len(news) - len(ids), nil
+}
+
+// insertedNews — ID вставленной новости и ее номер во входной пачке (с 1).
+type insertedNews struct {
+	id      int64
+	ordinal int64
+}
+
+// assignInsertedIDs проставляет ID новостям пачки по их номеру во входных
+// данных и возвращает вставленные ID.
+func assignInsertedIDs(news []models.NewsItem, inserted []insertedNews) []int64 {
+	ids := make([]int64, 0, len(inserted))
+	for _, row := range inserted {
+		if row.ordinal < 1 || row.ordinal > int64(len(news)) {
+			continue
+		}
+		news[row.ordinal-1].ID = row.id
+		ids = append(ids, row.id)
+	}
+	return ids
+}
+
+// LinkSources привязывает к уже сохраненным статьям новости (без ID), которые
+// пришли из другой ленты с той же канонической ссылкой. Возвращает ID статей,
+// получивших новый источник.
+func (r *newsRepository) LinkSources(ctx context.Context, news []models.NewsItem) ([]int64, error) {
+	var sourceIDs []int64
+	var guids, urls, canonicalURLs []string
+	for _, item := range news {
+		if item.ID != 0 {
+			continue
+		}
+		canonicalURL := item.CanonicalURL
+		if canonicalURL == "" {
+			canonicalURL = item.URL
+		}
+		sourceIDs = append(sourceIDs, item.SourceID)
+		guids = append(guids, item.GUID)
+		urls = append(urls, item.URL)
+		canonicalURLs = append(canonicalURLs, canonicalURL)
+	}
+	if len(sourceIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+        INSERT INTO news_item_sources (news_item_id, source_id, guid, url)
+        SELECT k.id, t.source_id, t.guid, t.url
+        FROM unnest($1::bigint[], $2::text[], $3::text[], $4::text[]) AS t(source_id, guid, url, canonical_url)
+        JOIN news_item_keys k ON k.canonical_url = t.canonical_url
+        ON CONFLICT DO NOTHING
+        RETURNING news_item_id
+    `
+
+	rows, err := r.pool.Query(ctx, query, sourceIDs, guids, urls, canonicalURLs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to link news sources: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan linked news id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to link news sources: %w", err)
+	}
+	return ids, nil
+}
+
+// AdoptSyntheticGUIDs переносит на уже сохраненные новости источника guid,
+// сгенерированные по канонической ссылке (для элементов ленты без guid).
+// Раньше такой guid считался от адреса ленты, ссылки и даты, и без переноса
+// сохраненные новости не находились бы по (source_id, guid). Меняются только
+// прежние синтетические guid (sha256 в hex) новостей с той же канонической
+// ссылкой.
+func (r *newsRepository) AdoptSyntheticGUIDs(ctx context.Context, news []models.NewsItem) (int64, error) {
+	var sourceIDs []int64
+	var guids, canonicalURLs []string
+	for _, item := range news {
+		if item.CanonicalURL == "" {
+			continue
+		}
+		sourceIDs = append(sourceIDs, item.SourceID)
+		guids = append(guids, item.GUID)
+		canonicalURLs = append(canonicalURLs, item.CanonicalURL)
+	}
+	if len(sourceIDs) == 0 {
+		return 0, nil
+	}
+
+	query := `
+        WITH t AS (
+            SELECT * FROM unnest($1::bigint[], $2::text[], $3::text[]) AS t(source_id, guid, canonical_url)
+            WHERE t.guid = encode(sha256(convert_to(t.canonical_url, 'UTF8')), 'hex')
+        ),
+        mapped AS (
+            UPDATE news_item_sources ms
+            SET guid = t.guid
+            FROM t
+            JOIN news_item_keys k ON k.canonical_url = t.canonical_url
+            WHERE ms.news_item_id = k.id AND ms.source_id = t.source_id
+              AND ms.guid <> t.guid AND ms.guid ~ '^[0-9a-f]{64}$'
+              AND NOT EXISTS (
+                  SELECT 1 FROM news_item_sources o WHERE o.source_id = t.source_id AND o.guid = t.guid
+              )
+            RETURNING ms.news_item_id, ms.source_id, ms.guid
+        ),
+        keys AS (
+            UPDATE news_item_keys k
+            SET guid = m.guid
+            FROM mapped m
+            WHERE k.id = m.news_item_id AND k.source_id = m.source_id
+              AND NOT EXISTS (
+                  SELECT 1 FROM news_item_keys o WHERE o.source_id = m.source_id AND o.guid = m.guid
+              )
+            RETURNING k.id, k.published_at, k.guid
+        ),
+        items AS (
+            UPDATE news_items ni
+            SET guid = k.guid
+            FROM keys k
+            WHERE ni.id = k.id AND ni.published_at = k.published_at
+        )
+        SELECT COUNT(*) FROM mapped
+    `
+
+	var adopted int64
+	if err := r.pool.QueryRow(ctx, query, sourceIDs, guids, canonicalURLs).Scan(&adopted); err != nil {
+		return 0, fmt.Errorf("failed to adopt synthetic guids: %w", err)
+	}
+	return adopted, nil
+}
+
+// MarkDuplicates помечает новости, у которых в другом источнике есть близкая
+// по отпечатку публикация в пределах окна window, как копии этой публикации.
+func (r *newsRepository) MarkDuplicates(ctx context.Context, newsIDs []int64, maxDistance int, window time.Duration) (int64, error) {
+	if len(newsIDs) == 0 {
+		return 0, nil
+	}
+
+	query := `
+        UPDATE news_items n
+        SET duplicate_of = d.original_id
+        FROM (
+            SELECT ni.id, (
+                SELECT o.id
+                FROM news_items o
+                WHERE o.source_id <> ni.source_id
+                  AND o.duplicate_of IS NULL
+                  AND o.fingerprint IS NOT NULL
+                  AND o.id < ni.id
+                  AND o.published_at BETWEEN ni.published_at - make_interval(secs => $3)
+                                         AND ni.published_at + make_interval(secs => $3)
+                  AND bit_count((o.fingerprint # ni.fingerprint)::bit(64)) <= $2
+                ORDER BY bit_count((o.fingerprint # ni.fingerprint)::bit(64)), o.published_at, o.id
+                LIMIT 1
+            ) AS original_id, ni.published_at
+            FROM news_items ni
+            JOIN news_item_keys k ON k.id = ni.id AND k.published_at = ni.published_at
+            WHERE k.id = ANY($1) AND ni.fingerprint IS NOT NULL
+              AND ni.published_at BETWEEN (SELECT MIN(published_at) FROM news_item_keys WHERE id = ANY($1))
+                                      AND (SELECT MAX(published_at) FROM news_item_keys WHERE id = ANY($1))
+        ) d
+        WHERE n.id = d.id AND n.published_at = d.published_at AND d.original_id IS NOT NULL
+    `
+
+	res, err := r.pool.Exec(ctx, query, newsIDs, maxDistance, window.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark duplicates: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+// CreateMedia сохраняет вложения только что вставленных новостей (с заполненным ID).
+func (r *newsRepository) CreateMedia(ctx context.Context, news []models.NewsItem) error {
+	var newsIDs []int64
+	var sizes []*int64
+	var urls, kinds []string
+	var mimeTypes []*string
+	var positions []int
+	for _, item := range news {
+		if item.ID == 0 {
+			continue
+		}
+		for _, media := range item.Media {
+			newsIDs = append(newsIDs, item.ID)
+			urls = append(urls, media.URL)
+			mimeTypes = append(mimeTypes, media.MimeType)
+			sizes = append(sizes, media.Size)
+			kinds = append(kinds, media.Kind)
+			positions = append(positions, media.Position)
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	query := `
+        INSERT INTO news_media (news_item_id, url, mime_type, size_bytes, kind, position)
+        SELECT t.news_item_id, t.url, LEFT(t.mime_type, 100), t.size_bytes, t.kind, t.position
+        FROM unnest($1::bigint[], $2::text[], $3::text[], $4::bigint[], $5::text[], $6::int[])
+            AS t(news_item_id, url, mime_type, size_bytes, kind, position)
+        ON CONFLICT (news_item_id, url) DO NOTHING
+    `
+
+	if _, err := r.pool.Exec(ctx, query, newsIDs, urls, mimeTypes, sizes, kinds, positions); err != nil {
+		return fmt.Errorf("failed to insert news media: %w", err)
+	}
+	return nil
+}
+
+// attachMedia загружает вложения для списка новостей одним запросом.
+func (r *newsRepository) attachMedia(ctx context.Context, news []models.NewsItem) error {
+	if len(news) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(news))
+	index := make(map[int64]int, len(news))
+	for i, item := range news {
+		ids[i] = item.ID
+		index[item.ID] = i
+	}
+
+	query := `
+        SELECT id, news_item_id, url, mime_type, size_bytes, kind, position
+        FROM news_media
+        WHERE news_item_id = ANY($1)
+        ORDER BY news_item_id, position
+    `
+
+	rows, err := r.pool.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get news media: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var media models.NewsMedia
+		if err := rows.Scan(
+			&media.ID,
+			&media.NewsItemID,
+			&media.URL,
+			&media.MimeType,
+			&media.Size,
+			&media.Kind,
+			&media.Position,
+		); err != nil {
+			return fmt.Errorf("failed to scan news media: %w", err)
+		}
+		i := index[media.NewsItemID]
+		news[i].Media = append(news[i].Media, media)
+	}
+	return rows.Err()
+}
+
+// CreateTags сохраняет рубрики и авторов только что вставленных новостей,
+// создавая недостающие теги.
+func (r *newsRepository) CreateTags(ctx context.Context, news []models.NewsItem) error {
+	var newsIDs []int64
+	var names, slugs, kinds []string
+	for _, item := range news {
+		if item.ID == 0 {
+			continue
+		}
+		for _, tag := range item.Tags {
+			newsIDs = append(newsIDs, item.ID)
+			names = append(names, tag.Name)
+			slugs = append(slugs, tag.Slug)
+			kinds = append(kinds, tag.Kind)
+		}
+	}
+	if len(newsIDs) == 0 {
+		return nil
+	}
+
+	query := `
+        WITH input AS (
+            SELECT * FROM unnest($1::bigint[], $2::text[], $3::text[], $4::text[]) AS t(news_item_id, name, slug, kind)
+        ),
+        created AS (
+            INSERT INTO tags (name, slug, kind)
+            SELECT DISTINCT ON (kind, slug) name, slug, kind
+            FROM input
+            ORDER BY kind, slug
+            ON CONFLICT (kind, slug) DO NOTHING
+            RETURNING id, slug, kind
+        ),
+        resolved AS (
+            SELECT id, slug, kind FROM created
+            UNION
+            SELECT t.id, t.slug, t.kind FROM tags t JOIN input i ON i.slug = t.slug AND i.kind = t.kind
+        )
+        INSERT INTO news_item_tags (news_item_id, tag_id)
+        SELECT DISTINCT i.news_item_id, r.id
+        FROM input i
+        JOIN resolved r ON r.slug = i.slug AND r.kind = i.kind
+        ON CONFLICT DO NOTHING
+    `
+
+	if _, err := r.pool.Exec(ctx, query, newsIDs, names, slugs, kinds); err != nil {
+		return fmt.Errorf("failed to insert news tags: %w", err)
+	}
+	return nil
+}
+
+// attachTags загружает рубрики и авторов для списка новостей одним запросом.
+func (r *newsRepository) attachTags(ctx context.Context, news []models.NewsItem) error {
+	if len(news) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(news))
+	index := make(map[int64]int, len(news))
+	for i, item := range news {
+		ids[i] = item.ID
+		index[item.ID] = i
+	}
+
+	query := `
+        SELECT nt.news_item_id, t.id, t.name, t.slug, t.kind
+        FROM news_item_tags nt
+        JOIN tags t ON t.id = nt.tag_id
+        WHERE nt.news_item_id = ANY($1)
+        ORDER BY nt.news_item_id, t.kind, t.name
+    `
+
+	rows, err := r.pool.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get news tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var newsID int64
+		var tag models.Tag
+		if err := rows.Scan(&newsID, &tag.ID, &tag.Name, &tag.Slug, &tag.Kind); err != nil {
+			return fmt.Errorf("failed to scan news tag: %w", err)
+		}
+		i := index[newsID]
+		news[i].Tags = append(news[i].Tags, tag)
+	}
+	return rows.Err()
+}
+
+// GetTags возвращает самые частые теги вида kind (или всех видов, если kind
+// пустой) с количеством новостей.
+func (r *newsRepository) GetTags(ctx context.Context, kind string, limit int) ([]models.Tag, error) {
+	query := `
+        SELECT t.id, t.name, t.slug, t.kind, COUNT(nt.news_item_id) AS news_count
+        FROM tags t
+        JOIN news_item_tags nt ON nt.tag_id = t.id
+        WHERE $1 = '' OR t.kind = $1
+        GROUP BY t.id
+        ORDER BY news_count DESC, t.name
+        LIMIT $2
+    `
+
+	rows, err := r.pool.Query(ctx, query, kind, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []models.Tag
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.Slug, &tag.Kind, &tag.NewsCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return tags, nil
+}
+
+// UpdateArticle сохраняет полный текст статьи, загруженный со страницы новости,
+// и пересчитывает по нему объем текста.
+func (r *newsRepository) UpdateArticle(ctx context.Context, newsID int64, articleHTML, articleText string, wordCount, readingMinutes int) error {
+	query := `
+        UPDATE news_items
+        SET article_html = $2, article_text = $3, word_count = $4, reading_time_minutes = $5
+        WHERE id = $1 AND published_at = (SELECT published_at FROM news_item_keys WHERE id = $1)
+    `
+
+	_, err := r.pool.Exec(ctx, query, newsID, articleHTML, articleText, wordCount, readingMinutes)
+	return err
+}
+
+// UpdateRevisions обновляет уже сохраненные новости, которые источник
+// исправил, и сохраняет прежнюю версию в news_item_revisions. Новость ищется
+// по guid в news_item_sources, а если guid сменился - по канонической ссылке
+// среди новостей, привязанных к источнику, поэтому учитываются и ленты, через
+// которые статья пришла второй. Правкой считается изменение хеша заголовка и
+// текста в той же ленте: хеш каждой ленты хранится в news_item_sources, и
+// разные тексты одной статьи в разных лентах правкой не считаются. Хеш,
+// которого раньше не было, только запоминается. Возвращает измененные новости.
+func (r *newsRepository) UpdateRevisions(ctx context.Context, news []models.NewsItem) ([]models.NewsEdit, error) {
+	if len(news) == 0 {
+		return nil, nil
+	}
+
+	sourceIDs := make([]int64, len(news))
+	guids := make([]string, len(news))
+	titles := make([]string, len(news))
+	contents := make([]*string, len(news))
+	contentTexts := make([]*string, len(news))
+	wordCounts := make([]int, len(news))
+	readingMinutes := make([]int, len(news))
+	fingerprints := make([]*int64, len(news))
+	hashes := make([]string, len(news))
+	canonicalURLs := make([]string, len(news))
+	for i, item := range news {
+		sourceIDs[i] = item.SourceID
+		guids[i] = item.GUID
+		titles[i] = item.Title
+		contents[i] = item.Content
+		contentTexts[i] = item.ContentText
+		wordCounts[i] = item.WordCount
+		readingMinutes[i] = item.ReadingMinutes
+		fingerprints[i] = item.Fingerprint
+		hashes[i] = item.ContentHash
+		canonicalURLs[i] = item.CanonicalURL
+		if canonicalURLs[i] == "" {
+			canonicalURLs[i] = item.URL
+		}
+	}
+
+	// Объем текста полнотекстовых новостей посчитан по статье, его не трогаем
+	query := `
+        WITH incoming AS (
+            SELECT DISTINCT ON (news_item_id, source_id) *
+            FROM (
+                SELECT t.*, COALESCE(
+                    (SELECT ms.news_item_id FROM news_item_sources ms
+                     WHERE ms.source_id = t.source_id AND ms.guid = t.guid),
+                    (SELECT ms.news_item_id FROM news_item_sources ms
+                     JOIN news_item_keys k ON k.id = ms.news_item_id
+                     WHERE ms.source_id = t.source_id AND k.canonical_url = t.canonical_url)
+                ) AS news_item_id
+                FROM unnest($1::bigint[], $2::text[], $3::text[], $4::text[], $5::text[], $6::int[], $7::int[], $8::bigint[], $9::text[], $10::text[])
+                    AS t(source_id, guid, title, content, content_text, word_count, reading_time_minutes, fingerprint, content_hash, canonical_url)
+            ) m
+            WHERE news_item_id IS NOT NULL
+        ),
+        matched AS (
+            SELECT i.*, ms.content_hash AS source_hash
+            FROM incoming i
+            JOIN news_item_sources ms ON ms.news_item_id = i.news_item_id AND ms.source_id = i.source_id
+        ),
+        hashed AS (
+            UPDATE news_item_sources ms
+            SET content_hash = m.content_hash
+            FROM matched m
+            WHERE ms.news_item_id = m.news_item_id AND ms.source_id = m.source_id
+              AND ms.content_hash IS DISTINCT FROM m.content_hash
+        ),
+        changed AS (
+            SELECT ni.id, ni.published_at, ni.title AS old_title, ni.content AS old_content, ni.content_text AS old_content_text,
+                   ni.content_hash AS old_hash, ni.fingerprint AS old_fingerprint,
+                   m.title, m.content, m.content_text, m.word_count, m.reading_time_minutes, m.fingerprint, m.content_hash
+            FROM matched m
+            JOIN news_item_keys k ON k.id = m.news_item_id
+            JOIN news_items ni ON ni.id = k.id AND ni.published_at = k.published_at
+            WHERE m.source_hash IS NOT NULL AND m.source_hash <> m.content_hash
+              AND ni.content_hash IS DISTINCT FROM m.content_hash
+            FOR UPDATE OF ni
+        ),
+        revisions AS (
+            INSERT INTO news_item_revisions (news_item_id, title, content, content_text, content_hash)
+            SELECT id, old_title, old_content, old_content_text, old_hash FROM changed
+        )
+        UPDATE news_items ni
+        SET title = LEFT(c.title, 500), content = c.content, content_text = c.content_text,
+            word_count = CASE WHEN ni.article_text IS NULL THEN c.word_count ELSE ni.word_count END,
+            reading_time_minutes = CASE WHEN ni.article_text IS NULL THEN c.reading_time_minutes ELSE ni.reading_time_minutes END,
+            fingerprint = c.fingerprint, content_hash = c.content_hash,
+            updated_at = NOW(), revision_count = ni.revision_count + 1
+        FROM changed c
+        WHERE ni.id = c.id AND ni.published_at = c.published_at
+        RETURNING ni.id, c.old_title, c.old_fingerprint, ni.title, ni.fingerprint
+    `
+
+	rows, err := r.pool.Query(ctx, query,
+		sourceIDs, guids, titles, contents, contentTexts, wordCounts, readingMinutes, fingerprints, hashes, canonicalURLs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update revised news: %w", err)
+	}
+	defer rows.Close()
+
+	var edits []models.NewsEdit
+	for rows.Next() {
+		var edit models.NewsEdit
+		if err := rows.Scan(&edit.NewsID, &edit.PreviousTitle, &edit.PreviousFingerprint, &edit.Title, &edit.Fingerprint); err != nil {
+			return nil, fmt.Errorf("failed to scan revised news: %w", err)
+		}
+		edits = append(edits, edit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to update revised news: %w", err)
+	}
+	return edits, nil
+}
+
 func (r *newsRepository) ExistsByGUID(ctx context.Context, sourceID int, guid string) (bool, error) {
 	query := `
         SELECT EXISTS(
-            SELECT 1 FROM news_items 
+            SELECT 1 FROM news_item_sources
             WHERE source_id = $1 AND guid = $2
         )
     `
@@ -147,14 +893,107 @@ func (r *newsRepository) Count(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
+// AddBookmark добавляет новость в закладки пользователя. Такие новости не
+// удаляются при очистке по сроку хранения.
+func (r *newsRepository) AddBookmark(ctx context.Context, userID, newsID int64) error {
+	query := `
+        INSERT INTO news_bookmarks (user_id, news_item_id)
+        VALUES ($1, $2)
+        ON CONFLICT (user_id, news_item_id) DO NOTHING
+    `
+	if _, err := r.pool.Exec(ctx, query, userID, newsID); err != nil {
+		return fmt.Errorf("failed to add bookmark: %w", err)
+	}
+	return nil
+}
+
+func (r *newsRepository) RemoveBookmark(ctx context.Context, userID, newsID int64) error {
+	query := `DELETE FROM news_bookmarks WHERE user_id = $1 AND news_item_id = $2`
+	if _, err := r.pool.Exec(ctx, query, userID, newsID); err != nil {
+		return fmt.Errorf("failed to remove bookmark: %w", err)
+	}
+	return nil
+}
+
+func (r *newsRepository) GetBookmarks(ctx context.Context, userID int64, page, pageSize int) ([]models.NewsItem, int64, error) {
+	query := `
+        SELECT ni.id, ni.title, ni.content, ni.url, ni.published_at, ni.source_id,
+               ni.content_text, ni.word_count, ni.reading_time_minutes,
+               ni.fetched_at, ni.updated_at, ni.revision_count,
+               COUNT(*) OVER() AS total_count
+        FROM news_bookmarks b
+        JOIN news_item_keys k ON k.id = b.news_item_id
+        JOIN news_items ni ON ni.id = k.id AND ni.published_at = k.published_at
+        WHERE b.user_id = $1
+        ORDER BY b.created_at DESC, ni.id DESC
+        LIMIT $2 OFFSET $3
+    `
+
+	rows, err := r.pool.Query(ctx, query, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	var news []models.NewsItem
+	var total int64
+	for rows.Next() {
+		var item models.NewsItem
+		if err := rows.Scan(
+			&item.ID,
+			&item.Title,
+			&item.Content,
+			&item.URL,
+			&item.PublishedAt,
+			&item.SourceID,
+			&item.ContentText,
+			&item.WordCount,
+			&item.ReadingMinutes,
+			&item.FetchedAt,
+			&item.UpdatedAt,
+			&item.RevisionCount,
+			&total,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan bookmark: %w", err)
+		}
+		news = append(news, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating rows: %w", err)
+	}
+	if err := r.attachMedia(ctx, news); err != nil {
+		return nil, 0, err
+	}
+	if err := r.attachTags(ctx, news); err != nil {
+		return nil, 0, err
+	}
+
+	return news, total, nil
+}
+
+// CreatePartitions создает недостающие месячные секции news_items на период
+// [from, until] и возвращает число созданных секций.
+func (r *newsRepository) CreatePartitions(ctx context.Context, from, until time.Time) (int, error) {
+	var created int
+	err := r.pool.QueryRow(ctx, "SELECT create_news_items_partitions($1, $2)", from, until).Scan(&created)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create news partitions: %w", err)
+	}
+	return created, nil
+}
+
 func (n *newsRepository) GetBySourceWithPagination(ctx context.Context, sourceID int64, offset, limit int) ([]models.NewsItem, int64, error) {
 	query := `
 		SELECT 
             ni.id, ni.title, ni.content, ni.url, ni.published_at, ni.source_id,
+            ni.content_text, ni.word_count, ni.reading_time_minutes,
+            ni.fetched_at, ni.updated_at, ni.revision_count,
             COUNT(*) OVER() as total_count
-        FROM news_items ni
-        JOIN sources s ON ni.source_id = s.id
-        WHERE ni.source_id = $1 AND s.is_active = true
+        FROM news_item_sources ms
+        JOIN sources s ON ms.source_id = s.id
+        JOIN news_item_keys k ON k.id = ms.news_item_id
+        JOIN news_items ni ON ni.id = k.id AND ni.published_at = k.published_at
+        WHERE ms.source_id = $1 AND s.is_active = true
         ORDER BY ni.published_at DESC
         LIMIT $2 OFFSET $3	
 	`
@@ -179,6 +1018,12 @@ func (n *newsRepository) GetBySourceWithPagination(ctx context.Context, sourceID
 			&item.URL,
 			&item.PublishedAt,
 			&item.SourceID,
+			&item.ContentText,
+			&item.WordCount,
+			&item.ReadingMinutes,
+			&item.FetchedAt,
+			&item.UpdatedAt,
+			&item.RevisionCount,
 			&total,
 		)
 
@@ -196,6 +1041,12 @@ func (n *newsRepository) GetBySourceWithPagination(ctx context.Context, sourceID
 	if err := rows.Err(); err != nil {
 		return nil, 0, fmt.Errorf("error iterating rows: %w", err)
 	}
+	if err := n.attachMedia(ctx, newsItems); err != nil {
+		return nil, 0, err
+	}
+	if err := n.attachTags(ctx, newsItems); err != nil {
+		return nil, 0, err
+	}
 
 	return newsItems, totalCount, nil
 }
@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignInsertedIDs_DuplicateCanonicalURLs(t *testing.T) {
+	// Две новости пачки с одной канонической ссылкой: вставлена только вторая
+	news := []models.NewsItem{
+		{GUID: "a", URL: "https://example.com/post?utm_source=rss", CanonicalURL: "https://example.com/post"},
+		{GUID: "b", URL: "https://example.com/post?utm_source=tg", CanonicalURL: "https://example.com/post"},
+		{GUID: "c", URL: "https://example.com/other", CanonicalURL: "https://example.com/other"},
+	}
+
+	ids := assignInsertedIDs(news, []insertedNews{{id: 10, ordinal: 2}, {id: 11, ordinal: 3}})
+
+	assert.Equal(t, []int64{10, 11}, ids)
+	assert.Zero(t, news[0].ID)
+	assert.Equal(t, int64(10), news[1].ID)
+	assert.Equal(t, int64(11), news[2].ID)
+}
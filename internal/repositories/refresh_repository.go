@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const refreshJobColumns = `id, user_id, created_at, status, result, error, finished_at`
+
+func scanRefreshJob(row pgx.Row) (*models.RefreshRequest, error) {
+	var job models.RefreshRequest
+	err := row.Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Timestamp,
+		&job.Status,
+		&job.Result,
+		&job.Error,
+		&job.FinishedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+type refreshRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRefreshRepository(pool *pgxpool.Pool) RefreshRepository {
+	return &refreshRepository{pool: pool}
+}
+
+func (r *refreshRepository) CountQueued(ctx context.Context) (int, error) {
+	var count int
+	err := r.pool.QueryRow(ctx, "SELECT COUNT(*) FROM refresh_jobs WHERE status = 'queued'").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count refresh jobs: %w", err)
+	}
+	return count, nil
+}
+
+// Enqueue ставит задачу обновления, если с прошлого запроса пользователя
+// прошло не меньше minGap. Время запроса фиксируется в refresh_cooldowns под
+// блокировкой строки, поэтому одновременные запросы из разных процессов не
+// обходят ограничение. Возвращает nil, если ждать еще нужно.
+func (r *refreshRepository) Enqueue(ctx context.Context, id string, userID int64, minGap time.Duration) (*models.RefreshRequest, error) {
+	query := `
+        WITH cooldown AS (
+            INSERT INTO refresh_cooldowns (user_id, last_requested_at)
+            VALUES ($2, NOW())
+            ON CONFLICT (user_id) DO UPDATE SET last_requested_at = EXCLUDED.last_requested_at
+            WHERE refresh_cooldowns.last_requested_at <= NOW() - make_interval(secs => $3)
+            RETURNING user_id
+        )
+        INSERT INTO refresh_jobs (id, user_id)
+        SELECT $1::uuid, user_id FROM cooldown
+        RETURNING ` + refreshJobColumns
+
+	job, err := scanRefreshJob(r.pool.QueryRow(ctx, query, id, userID, minGap.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue refresh job: %w", err)
+	}
+	return job, nil
+}
+
+// Claim забирает самую старую задачу из очереди. Задачи, зависшие у
+// остановленного процесса дольше staleAfter, забираются повторно, пока число
+// попыток меньше maxAttempts. Возвращает nil, если очередь пуста.
+func (r *refreshRepository) Claim(ctx context.Context, staleAfter time.Duration, maxAttempts int) (*models.RefreshRequest, error) {
+	query := `
+        UPDATE refresh_jobs
+        SET status = 'processing', claimed_at = NOW(), attempts = attempts + 1
+        WHERE id = (
+            SELECT id
+            FROM refresh_jobs
+            WHERE status = 'queued'
+               OR (status = 'processing' AND claimed_at < NOW() - make_interval(secs => $1) AND attempts < $2)
+            ORDER BY created_at
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING ` + refreshJobColumns
+
+	job, err := scanRefreshJob(r.pool.QueryRow(ctx, query, staleAfter.Seconds(), maxAttempts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim refresh job: %w", err)
+	}
+	return job, nil
+}
+
+func (r *refreshRepository) Complete(ctx context.Context, id string, result int) error {
+	query := `
+        UPDATE refresh_jobs
+        SET status = 'completed', result = $2, error = NULL, finished_at = NOW()
+        WHERE id = $1
+    `
+	if _, err := r.pool.Exec(ctx, query, id, result); err != nil {
+		return fmt.Errorf("failed to complete refresh job: %w", err)
+	}
+	return nil
+}
+
+func (r *refreshRepository) Fail(ctx context.Context, id string, lastError string) error {
+	query := `
+        UPDATE refresh_jobs
+        SET status = 'failed', error = $2, finished_at = NOW()
+        WHERE id = $1
+    `
+	if _, err := r.pool.Exec(ctx, query, id, lastError); err != nil {
+		return fmt.Errorf("failed to fail refresh job: %w", err)
+	}
+	return nil
+}
+
+func (r *refreshRepository) GetByID(ctx context.Context, id string) (*models.RefreshRequest, error) {
+	query := `SELECT ` + refreshJobColumns + ` FROM refresh_jobs WHERE id = $1`
+
+	job, err := scanRefreshJob(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh job: %w", err)
+	}
+	return job, nil
+}
+
+// FailStale завершает с ошибкой зависшие задачи, у которых закончились попытки.
+func (r *refreshRepository) FailStale(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int64, error) {
+	query := `
+        UPDATE refresh_jobs
+        SET status = 'failed', error = 'refresh worker stopped', finished_at = NOW()
+        WHERE status = 'processing'
+          AND claimed_at < NOW() - make_interval(secs => $1)
+          AND attempts >= $2
+    `
+	res, err := r.pool.Exec(ctx, query, staleAfter.Seconds(), maxAttempts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail stale refresh jobs: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+// DeleteFinished удаляет завершенные задачи и истекшие ограничения частоты
+// запросов старше olderThan.
+func (r *refreshRepository) DeleteFinished(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := `
+        WITH cooldowns AS (
+            DELETE FROM refresh_cooldowns
+            WHERE last_requested_at < NOW() - make_interval(secs => $1)
+        )
+        DELETE FROM refresh_jobs
+        WHERE status IN ('completed', 'failed') AND created_at < NOW() - make_interval(secs => $1)
+    `
+	res, err := r.pool.Exec(ctx, query, olderThan.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete finished refresh jobs: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
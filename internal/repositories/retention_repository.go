@@ -0,0 +1,275 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type retentionRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRetentionRepository(pool *pgxpool.Pool) RetentionRepository {
+	return &retentionRepository{pool: pool}
+}
+
+// PurgeExpired удаляет одну порцию новостей, срок хранения которых истек во
+// всех связанных источниках (retention_days или defaultDays, 0 - хранить
+// всегда), считая от времени получения. Новости в закладках не удаляются. При
+// archive удаленные новости переносятся в news_items_archive. Ключи новости в
+// news_item_keys и news_item_sources остаются, чтобы статья, которая все еще
+// есть в ленте, не была сохранена и разослана заново; позже их удаляет
+// PurgeExpiredKeys.
+func (r *retentionRepository) PurgeExpired(ctx context.Context, defaultDays, batchSize int, archive bool) (int64, int64, error) {
+	query := `
+        WITH expired AS (
+            SELECT ni.id, ni.title, ni.content, ni.content_text, ni.article_text, ni.url,
+                   ni.published_at, ni.fetched_at, ni.source_id, ni.guid
+            FROM news_items ni
+            WHERE ni.fetched_at < NOW() - make_interval(days => (
+                      SELECT MIN(COALESCE(retention_days, $1)) FROM sources
+                      WHERE COALESCE(retention_days, $1) > 0
+                  ))
+              AND NOT EXISTS (
+                  SELECT 1 FROM sources s
+                  WHERE (s.id = ni.source_id OR s.id IN (
+                            SELECT ms.source_id FROM news_item_sources ms WHERE ms.news_item_id = ni.id
+                        ))
+                    AND (COALESCE(s.retention_days, $1) = 0
+                         OR ni.fetched_at >= NOW() - make_interval(days => COALESCE(s.retention_days, $1)))
+              )
+              AND NOT EXISTS (SELECT 1 FROM news_bookmarks b WHERE b.news_item_id = ni.id)
+            ORDER BY ni.id
+            LIMIT $2
+            FOR UPDATE OF ni SKIP LOCKED
+        ),
+        removed AS (
+            DELETE FROM news_items ni
+            USING expired e
+            WHERE ni.id = e.id AND ni.published_at = e.published_at
+            RETURNING ni.id
+        ),
+        removed_deliveries AS (
+            DELETE FROM news_deliveries d USING removed r WHERE d.news_item_id = r.id
+        ),
+        removed_media AS (
+            DELETE FROM news_media m USING removed r WHERE m.news_item_id = r.id
+        ),
+        removed_tags AS (
+            DELETE FROM news_item_tags t USING removed r WHERE t.news_item_id = r.id
+        ),
+        removed_revisions AS (
+            DELETE FROM news_item_revisions v USING removed r WHERE v.news_item_id = r.id
+        ),
+        archived AS (
+            INSERT INTO news_items_archive
+            (id, title, content, content_text, article_text, url, published_at, fetched_at, source_id, guid)
+            SELECT e.id, e.title, e.content, e.content_text, e.article_text, e.url,
+                   e.published_at, e.fetched_at, e.source_id, e.guid
+            FROM expired e
+            JOIN removed r ON r.id = e.id
+            WHERE $3
+            ON CONFLICT (id) DO NOTHING
+            RETURNING 1
+        )
+        SELECT (SELECT COUNT(*) FROM removed), (SELECT COUNT(*) FROM archived)
+    `
+
+	var removed, archived int64
+	if err := r.pool.QueryRow(ctx, query, defaultDays, batchSize, archive).Scan(&removed, &archived); err != nil {
+		return 0, 0, fmt.Errorf("failed to purge expired news: %w", err)
+	}
+	return removed, archived, nil
+}
+
+// PurgeExpiredKeys удаляет одну порцию ключей дедупликации новостей, текст
+// которых уже удален очисткой, если во всех лентах статья впервые появилась
+// раньше срока хранения плюс graceDays: такие статьи ленты уже не отдают.
+// Ключи источников с бессрочным хранением не удаляются.
+func (r *retentionRepository) PurgeExpiredKeys(ctx context.Context, defaultDays, graceDays, batchSize int) (int64, error) {
+	query := `
+        WITH expired AS (
+            SELECT k.id
+            FROM news_item_keys k
+            WHERE NOT EXISTS (
+                      SELECT 1 FROM news_items ni WHERE ni.id = k.id AND ni.published_at = k.published_at
+                  )
+              AND NOT EXISTS (
+                  SELECT 1
+                  FROM news_item_sources ms
+                  JOIN sources s ON s.id = ms.source_id
+                  WHERE ms.news_item_id = k.id
+                    AND (COALESCE(s.retention_days, $1) = 0
+                         OR ms.linked_at >= NOW() - make_interval(days => COALESCE(s.retention_days, $1) + $2))
+              )
+            ORDER BY k.id
+            LIMIT $3
+            FOR UPDATE OF k SKIP LOCKED
+        )
+        DELETE FROM news_item_keys k
+        USING expired e
+        WHERE k.id = e.id
+    `
+
+	res, err := r.pool.Exec(ctx, query, defaultDays, graceDays, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired news keys: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+const retentionRunColumns = `id, trigger, mode, status, requested_at, started_at, finished_at,
+               removed_count, archived_count, error`
+
+func scanRetentionRun(row pgx.Row) (*models.RetentionRun, error) {
+	var run models.RetentionRun
+	err := row.Scan(
+		&run.ID,
+		&run.Trigger,
+		&run.Mode,
+		&run.Status,
+		&run.RequestedAt,
+		&run.StartedAt,
+		&run.FinishedAt,
+		&run.RemovedCount,
+		&run.ArchivedCount,
+		&run.Error,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// CreateRun записывает запуск, который начинается сразу.
+func (r *retentionRepository) CreateRun(ctx context.Context, run *models.RetentionRun) error {
+	query := `
+        INSERT INTO retention_runs (trigger, mode, status, started_at)
+        VALUES ($1, $2, 'running', NOW())
+        RETURNING id, status, requested_at, started_at
+    `
+	return r.pool.QueryRow(ctx, query, run.Trigger, run.Mode).Scan(&run.ID, &run.Status, &run.RequestedAt, &run.StartedAt)
+}
+
+// EnqueueRun ставит ручной запуск в очередь. Если запуск уже ждет в очереди,
+// возвращается он.
+func (r *retentionRepository) EnqueueRun(ctx context.Context, mode string) (*models.RetentionRun, error) {
+	query := `
+        INSERT INTO retention_runs (trigger, mode, status)
+        VALUES ('manual', $1, 'queued')
+        ON CONFLICT DO NOTHING
+        RETURNING ` + retentionRunColumns
+
+	run, err := scanRetentionRun(r.pool.QueryRow(ctx, query, mode))
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue retention run: %w", err)
+	}
+	if run != nil {
+		return run, nil
+	}
+
+	query = `SELECT ` + retentionRunColumns + ` FROM retention_runs WHERE status = 'queued'`
+	run, err = scanRetentionRun(r.pool.QueryRow(ctx, query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queued retention run: %w", err)
+	}
+	if run == nil {
+		// Запуск из очереди только что забран: ставим новый
+		return r.EnqueueRun(ctx, mode)
+	}
+	return run, nil
+}
+
+// ClaimQueuedRun забирает запуск из очереди. Возвращает nil, если очередь пуста.
+func (r *retentionRepository) ClaimQueuedRun(ctx context.Context) (*models.RetentionRun, error) {
+	query := `
+        UPDATE retention_runs
+        SET status = 'running', started_at = NOW()
+        WHERE id = (
+            SELECT id FROM retention_runs
+            WHERE status = 'queued'
+            ORDER BY requested_at
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING ` + retentionRunColumns
+
+	run, err := scanRetentionRun(r.pool.QueryRow(ctx, query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim retention run: %w", err)
+	}
+	return run, nil
+}
+
+// FailInterruptedRuns завершает с ошибкой запуски, оставшиеся от прежнего
+// лидера. Вызывается новым лидером до начала работы, когда ни один запуск
+// выполняться не может.
+func (r *retentionRepository) FailInterruptedRuns(ctx context.Context) (int64, error) {
+	query := `
+        UPDATE retention_runs
+        SET status = 'failed', finished_at = NOW(), error = COALESCE(error, 'interrupted')
+        WHERE status = 'running'
+    `
+	res, err := r.pool.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail interrupted retention runs: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+func (r *retentionRepository) FinishRun(ctx context.Context, run *models.RetentionRun) error {
+	query := `
+        UPDATE retention_runs
+        SET finished_at = NOW(), removed_count = $2, archived_count = $3, error = $4,
+            status = CASE WHEN $4::text IS NULL THEN 'completed' ELSE 'failed' END
+        WHERE id = $1
+        RETURNING status, finished_at
+    `
+	return r.pool.QueryRow(ctx, query, run.ID, run.RemovedCount, run.ArchivedCount, run.Error).Scan(&run.Status, &run.FinishedAt)
+}
+
+func (r *retentionRepository) GetRun(ctx context.Context, id int64) (*models.RetentionRun, error) {
+	query := `SELECT ` + retentionRunColumns + ` FROM retention_runs WHERE id = $1`
+	run, err := scanRetentionRun(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention run: %w", err)
+	}
+	return run, nil
+}
+
+func (r *retentionRepository) GetRuns(ctx context.Context, limit int) ([]models.RetentionRun, error) {
+	query := `
+        SELECT ` + retentionRunColumns + `
+        FROM retention_runs
+        ORDER BY requested_at DESC
+        LIMIT $1
+    `
+
+	rows, err := r.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.RetentionRun
+	for rows.Next() {
+		run, err := scanRetentionRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan retention run: %w", err)
+		}
+		runs = append(runs, *run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return runs, nil
+}
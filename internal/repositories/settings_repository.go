@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type settingsRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSettingsRepository(pool *pgxpool.Pool) SettingsRepository {
+	return &settingsRepository{pool: pool}
+}
+
+func (r *settingsRepository) GetByUserID(ctx context.Context, userID int64) (*models.UserSettings, error) {
+	query := `
+        SELECT user_id, delivery_mode, digest_time, digest_weekday, timezone, group_by,
+               last_digest_at, next_digest_at
+        FROM user_settings
+        WHERE user_id = $1
+    `
+
+	var settings models.UserSettings
+	err := r.pool.QueryRow(ctx, query, userID).Scan(
+		&settings.UserID,
+		&settings.DeliveryMode,
+		&settings.DigestTime,
+		&settings.DigestWeekday,
+		&settings.Timezone,
+		&settings.GroupBy,
+		&settings.LastDigestAt,
+		&settings.NextDigestAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func (r *settingsRepository) Upsert(ctx context.Context, settings *models.UserSettings) error {
+	query := `
+        INSERT INTO user_settings
+        (user_id, delivery_mode, digest_time, digest_weekday, timezone, group_by, next_digest_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (user_id) DO UPDATE
+        SET delivery_mode = EXCLUDED.delivery_mode,
+            digest_time = EXCLUDED.digest_time,
+            digest_weekday = EXCLUDED.digest_weekday,
+            timezone = EXCLUDED.timezone,
+            group_by = EXCLUDED.group_by,
+            next_digest_at = EXCLUDED.next_digest_at
+    `
+
+	_, err := r.pool.Exec(ctx, query,
+		settings.UserID,
+		settings.DeliveryMode,
+		settings.DigestTime,
+		settings.DigestWeekday,
+		settings.Timezone,
+		settings.GroupBy,
+		settings.NextDigestAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save user settings: %w", err)
+	}
+	return nil
+}
+
+func (r *settingsRepository) GetDueDigests(ctx context.Context, now time.Time) ([]models.UserSettings, error) {
+	query := `
+        SELECT user_id, delivery_mode, digest_time, digest_weekday, timezone, group_by,
+               last_digest_at, next_digest_at
+        FROM user_settings
+        WHERE delivery_mode <> 'instant' AND next_digest_at <= $1
+        ORDER BY next_digest_at
+    `
+
+	rows, err := r.pool.Query(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due digests: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.UserSettings
+	for rows.Next() {
+		var settings models.UserSettings
+		if err := rows.Scan(
+			&settings.UserID,
+			&settings.DeliveryMode,
+			&settings.DigestTime,
+			&settings.DigestWeekday,
+			&settings.Timezone,
+			&settings.GroupBy,
+			&settings.LastDigestAt,
+			&settings.NextDigestAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan user settings: %w", err)
+		}
+		result = append(result, settings)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *settingsRepository) ScheduleNextDigest(ctx context.Context, userID int64, sentAt *time.Time, nextDigestAt *time.Time) error {
+	query := `
+        UPDATE user_settings
+        SET last_digest_at = COALESCE($2, last_digest_at), next_digest_at = $3
+        WHERE user_id = $1
+    `
+	_, err := r.pool.Exec(ctx, query, userID, sentAt, nextDigestAt)
+	return err
+}
@@ -6,12 +6,38 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const sourceColumns = `s.id, s.name, s.url, s.canonical_url, s.category_id, s.is_active, s.etag, s.last_modified,
+            s.fetch_interval_seconds, s.min_fetch_interval_seconds, s.max_fetch_interval_seconds, s.next_fetch_at,
+            s.fetch_full_text, s.encoding, s.retention_days`
+
+func scanSource(row pgx.Row, source *models.Source, extra ...any) error {
+	dest := []any{
+		&source.ID,
+		&source.Name,
+		&source.URL,
+		&source.CanonicalURL,
+		&source.CategoryID,
+		&source.IsActive,
+		&source.ETag,
+		&source.LastModified,
+		&source.FetchInterval,
+		&source.MinFetchInterval,
+		&source.MaxFetchInterval,
+		&source.NextFetchAt,
+		&source.FetchFullText,
+		&source.Encoding,
+		&source.RetentionDays,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
 type sourceRepository struct {
 	pool *pgxpool.Pool
 }
@@ -22,10 +48,10 @@ func NewSourceRepository(pool *pgxpool.Pool) SourceRepository {
 
 func (r *sourceRepository) GetActive(ctx context.Context) ([]models.Source, error) {
 	query := `
-        SELECT id, name, url, category_id, is_active
-        FROM sources
-        WHERE is_active = true
-        ORDER BY name
+        SELECT ` + sourceColumns + `
+        FROM sources s
+        WHERE s.is_active = true
+        ORDER BY s.name
     `
 
 	rows, err := r.pool.Query(ctx, query)
@@ -37,13 +63,7 @@ func (r *sourceRepository) GetActive(ctx context.Context) ([]models.Source, erro
 	var sources []models.Source
 	for rows.Next() {
 		var source models.Source
-		err := rows.Scan(
-			&source.ID,
-			&source.Name,
-			&source.URL,
-			&source.CategoryID,
-			&source.IsActive,
-		)
+		err := scanSource(rows, &source)
 		if err != nil {
 			return nil, err
 		}
@@ -53,22 +73,45 @@ func (r *sourceRepository) GetActive(ctx context.Context) ([]models.Source, erro
 	return sources, nil
 }
 
+func (r *sourceRepository) GetDue(ctx context.Context, now time.Time) ([]models.Source, error) {
+	query := `
+        SELECT ` + sourceColumns + `
+        FROM sources s
+        WHERE s.is_active = true AND (s.next_fetch_at IS NULL OR s.next_fetch_at <= $1)
+        ORDER BY s.next_fetch_at NULLS FIRST
+    `
+
+	rows, err := r.pool.Query(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due sources: %w", err)
+	}
+	defer rows.Close()
+
+	var sources []models.Source
+	for rows.Next() {
+		var source models.Source
+		if err := scanSource(rows, &source); err != nil {
+			return nil, fmt.Errorf("failed to scan source: %w", err)
+		}
+		sources = append(sources, source)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return sources, nil
+}
+
 func (r *sourceRepository) GetByID(ctx context.Context, id int) (*models.Source, error) {
 	log.Println("GetByID")
 	query := `
-        SELECT id, name, url, category_id, is_active
-        FROM sources
-        WHERE id = $1
+        SELECT ` + sourceColumns + `
+        FROM sources s
+        WHERE s.id = $1
     `
 
 	var source models.Source
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&source.ID,
-		&source.Name,
-		&source.URL,
-		&source.CategoryID,
-		&source.IsActive,
-	)
+	err := scanSource(r.pool.QueryRow(ctx, query, id), &source)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
@@ -78,17 +121,11 @@ func (r *sourceRepository) GetByID(ctx context.Context, id int) (*models.Source,
 }
 
 func (r *sourceRepository) GetByURL(ctx context.Context, url string) (*models.Source, error) {
-	query := `SELECT id, name, url, category_id, is_active,
-              FROM sources WHERE url = $1`
+	query := `SELECT ` + sourceColumns + `
+              FROM sources s WHERE s.canonical_url = $1`
 
 	var source models.Source
-	err := r.pool.QueryRow(ctx, query, url).Scan(
-		&source.ID,
-		&source.Name,
-		&source.URL,
-		&source.CategoryID,
-		&source.IsActive,
-	)
+	err := scanSource(r.pool.QueryRow(ctx, query, url), &source)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -102,8 +139,8 @@ func (r *sourceRepository) GetByURL(ctx context.Context, url string) (*models.So
 
 func (r *sourceRepository) Create(ctx context.Context, source *models.Source) error {
 	query := `
-        INSERT INTO sources (name, url, category_id, is_active)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO sources (name, url, category_id, is_active, canonical_url)
+        VALUES ($1, $2, $3, $4, $5)
         RETURNING id
     `
 
@@ -112,13 +149,19 @@ func (r *sourceRepository) Create(ctx context.Context, source *models.Source) er
 		source.URL,
 		source.CategoryID,
 		source.IsActive,
+		source.CanonicalURL,
 	).Scan(&source.ID)
 }
 
 func (r *sourceRepository) Update(ctx context.Context, source *models.Source) error {
 	query := `
         UPDATE sources
-        SET name = $1, url = $2, category_id = $3, is_active = $4
+        SET name = $1, url = $2, category_id = $3,
+            consecutive_failures = CASE WHEN $4 AND NOT is_active THEN 0 ELSE consecutive_failures END,
+            is_active = $4,
+            min_fetch_interval_seconds = $6, max_fetch_interval_seconds = $7,
+            canonical_url = $8, fetch_full_text = $9, encoding = $10,
+            retention_days = $11
         WHERE id = $5
     `
 
@@ -128,20 +171,60 @@ func (r *sourceRepository) Update(ctx context.Context, source *models.Source) er
 		source.CategoryID,
 		source.IsActive,
 		source.ID,
+		source.MinFetchInterval,
+		source.MaxFetchInterval,
+		source.CanonicalURL,
+		source.FetchFullText,
+		source.Encoding,
+		source.RetentionDays,
 	)
 
 	return err
 }
 
+// Delete удаляет источник. Статьи, которые пришли и из других лент,
+// переходят к самой ранней из оставшихся лент; каскадно удаляются только
+// статьи, у которых других лент нет.
 func (r *sourceRepository) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM sources WHERE id = $1`
-	_, err := r.pool.Exec(ctx, query, id)
-	return err
+	reassignQuery := `
+        WITH heir AS (
+            SELECT DISTINCT ON (ms.news_item_id) ms.news_item_id, ms.source_id, ms.guid
+            FROM news_item_keys k
+            JOIN news_item_sources ms ON ms.news_item_id = k.id AND ms.source_id <> $1
+            WHERE k.source_id = $1
+            ORDER BY ms.news_item_id, ms.linked_at, ms.source_id
+        ),
+        reassigned AS (
+            UPDATE news_item_keys k
+            SET source_id = h.source_id, guid = h.guid
+            FROM heir h
+            WHERE k.id = h.news_item_id
+            RETURNING k.id, k.published_at, k.source_id, k.guid
+        )
+        UPDATE news_items n
+        SET source_id = r.source_id, guid = r.guid
+        FROM reassigned r
+        WHERE n.id = r.id AND n.published_at = r.published_at
+    `
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, reassignQuery, id); err != nil {
+		return fmt.Errorf("failed to reassign shared news: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM sources WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete source: %w", err)
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *sourceRepository) GetActiveForUser(ctx context.Context, userID int64) ([]models.Source, error) {
 	query := `
-        SELECT s.id, s.name, s.url, s.category_id, s.is_active
+        SELECT ` + sourceColumns + `
         FROM sources s
         INNER JOIN user_sources us ON s.id = us.source_id
         WHERE us.user_id = $1 AND s.is_active = true
@@ -157,13 +240,7 @@ func (r *sourceRepository) GetActiveForUser(ctx context.Context, userID int64) (
 	var sources []models.Source
 	for rows.Next() {
 		var source models.Source
-		err := rows.Scan(
-			&source.ID,
-			&source.Name,
-			&source.URL,
-			&source.CategoryID,
-			&source.IsActive,
-		)
+		err := scanSource(rows, &source)
 		if err != nil {
 			return nil, err
 		}
@@ -176,10 +253,10 @@ func (r *sourceRepository) GetActiveForUser(ctx context.Context, userID int64) (
 func (r *sourceRepository) GetAllWithPagination(ctx context.Context, page, pageSize int) ([]models.Source, int64, error) {
 	query := `
         SELECT 
-            id, name, url, category_id, is_active,
+            ` + sourceColumns + `,
             COUNT(*) OVER() as total_count
-        FROM sources 
-        ORDER BY name
+        FROM sources s
+        ORDER BY s.name
         LIMIT $1 OFFSET $2
     `
 	offset := (page - 1) * pageSize
@@ -197,14 +274,7 @@ func (r *sourceRepository) GetAllWithPagination(ctx context.Context, page, pageS
 		var source models.Source
 		var total sql.NullInt64
 
-		err := rows.Scan(
-			&source.ID,
-			&source.Name,
-			&source.URL,
-			&source.CategoryID,
-			&source.IsActive,
-			&total,
-		)
+		err := scanSource(rows, &source, &total)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan source: %w", err)
 		}
@@ -224,3 +294,128 @@ func (r *sourceRepository) GetAllWithPagination(ctx context.Context, page, pageS
 
 	return sources, totalCount, nil
 }
+
+func (r *sourceRepository) UpdateFetchCache(ctx context.Context, sourceID int64, etag, lastModified string) error {
+	query := `
+        UPDATE sources
+        SET etag = NULLIF($2, ''), last_modified = NULLIF($3, '')
+        WHERE id = $1
+    `
+	_, err := r.pool.Exec(ctx, query, sourceID, etag, lastModified)
+	return err
+}
+
+func (r *sourceRepository) RecordFetchSuccess(ctx context.Context, sourceID int64, statusCode int, latency time.Duration) error {
+	query := `
+        UPDATE sources
+        SET last_fetched_at = NOW(),
+            last_success_at = NOW(),
+            last_error = NULL,
+            consecutive_failures = 0,
+            last_http_status = $2,
+            avg_fetch_latency_ms = CASE
+                WHEN avg_fetch_latency_ms IS NULL THEN $3
+                ELSE ROUND(avg_fetch_latency_ms * 0.8 + $3 * 0.2)
+            END
+        WHERE id = $1
+    `
+	_, err := r.pool.Exec(ctx, query, sourceID, statusCode, latency.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to record fetch success: %w", err)
+	}
+	return nil
+}
+
+func (r *sourceRepository) RecordFetchFailure(ctx context.Context, sourceID int64, statusCode int, lastError string, maxFailures int) (bool, error) {
+	query := `
+        UPDATE sources
+        SET last_fetched_at = NOW(),
+            last_error = $3,
+            consecutive_failures = consecutive_failures + 1,
+            last_http_status = NULLIF($2, 0),
+            is_active = CASE
+                WHEN $4 > 0 AND consecutive_failures + 1 >= $4 THEN false
+                ELSE is_active
+            END
+        WHERE id = $1
+        RETURNING is_active
+    `
+
+	var isActive bool
+	err := r.pool.QueryRow(ctx, query, sourceID, statusCode, lastError, maxFailures).Scan(&isActive)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record fetch failure: %w", err)
+	}
+	return !isActive, nil
+}
+
+func (r *sourceRepository) UpdateURL(ctx context.Context, sourceID int64, url, canonicalURL string) error {
+	query := `UPDATE sources SET url = $2, canonical_url = $3 WHERE id = $1`
+	_, err := r.pool.Exec(ctx, query, sourceID, url, canonicalURL)
+	if err != nil {
+		return fmt.Errorf("failed to update source url: %w", err)
+	}
+	return nil
+}
+
+func (r *sourceRepository) GetHealth(ctx context.Context, onlyFailing bool, page, pageSize int) ([]models.SourceHealth, int64, error) {
+	query := `
+        SELECT id, name, url, is_active, last_fetched_at, last_success_at, last_error,
+               consecutive_failures, last_http_status, avg_fetch_latency_ms,
+               COUNT(*) OVER() as total_count
+        FROM sources
+        WHERE NOT $1 OR consecutive_failures > 0
+        ORDER BY consecutive_failures DESC, last_success_at ASC NULLS FIRST, name
+        LIMIT $2 OFFSET $3
+    `
+	offset := (page - 1) * pageSize
+
+	rows, err := r.pool.Query(ctx, query, onlyFailing, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get source health: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.SourceHealth
+	var totalCount int64
+	for rows.Next() {
+		var health models.SourceHealth
+		if err := rows.Scan(
+			&health.SourceID,
+			&health.Name,
+			&health.URL,
+			&health.IsActive,
+			&health.LastFetchedAt,
+			&health.LastSuccessAt,
+			&health.LastError,
+			&health.ConsecutiveFailures,
+			&health.LastHTTPStatus,
+			&health.AvgFetchLatencyMs,
+			&totalCount,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan source health: %w", err)
+		}
+		result = append(result, health)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return result, totalCount, nil
+}
+
+func (r *sourceRepository) ScheduleNextFetch(ctx context.Context, sourceID int64, interval time.Duration, nextFetchAt time.Time) error {
+	query := `
+        UPDATE sources
+        SET fetch_interval_seconds = $2, next_fetch_at = $3
+        WHERE id = $1
+    `
+	_, err := r.pool.Exec(ctx, query, sourceID, int(interval.Seconds()), nextFetchAt)
+	if err != nil {
+		return fmt.Errorf("failed to schedule next fetch: %w", err)
+	}
+	return nil
+}
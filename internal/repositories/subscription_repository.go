@@ -19,7 +19,7 @@ func NewSubscriptionRepository(pool *pgxpool.Pool) *subscriptionRepository {
 
 func (s *subscriptionRepository) GetUserSubscriptions(ctx context.Context, userID int64) ([]models.Source, error) {
 	query := `
-        SELECT s.id, s.name, s.url, s.category_id, s.is_active
+        SELECT ` + sourceColumns + `
         FROM sources s
         JOIN user_sources us ON s.id = us.source_id
         WHERE us.user_id = $1 AND s.is_active = true
@@ -34,13 +34,7 @@ func (s *subscriptionRepository) GetUserSubscriptions(ctx context.Context, userI
 	var sources []models.Source
 	for rows.Next() {
 		var source models.Source
-		if err := rows.Scan(
-			&source.ID,
-			&source.Name,
-			&source.URL,
-			&source.CategoryID,
-			&source.IsActive,
-		); err != nil {
+		if err := scanSource(rows, &source); err != nil {
 			return nil, err
 		}
 		sources = append(sources, source)
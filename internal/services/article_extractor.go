@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	maxArticleSize       = 5 << 20
+	minArticleTextLength = 200
+	minParagraphLength   = 25
+	maxArticlesPerFetch  = 20
+)
+
+var ErrArticleNotFound = errors.New("article content not found")
+
+var (
+	positiveCandidate = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story`)
+	negativeCandidate = regexp.MustCompile(`(?i)comment|meta|footer|footnote|sidebar|share|related|promo|advert|banner|nav|menu|social|widget|subscribe|popup|cookie`)
+)
+
+// Теги, которые никогда не входят в текст статьи.
+var articleNoiseTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Iframe:   true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Svg:      true,
+}
+
+type ArticleExtractor struct {
+	client *http.Client
+}
+
+func NewArticleExtractor(guard *NetworkGuard) *ArticleExtractor {
+	return &ArticleExtractor{
+		client: guardOrDefault(guard).Client(10*time.Second, 15*time.Second, nil),
+	}
+}
+
+// Extract загружает страницу статьи и выделяет из нее основной текст.
+func (e *ArticleExtractor) Extract(ctx context.Context, pageURL string) (ProcessedContent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return ProcessedContent{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html, application/xhtml+xml;q=0.9, */*;q=0.5")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return ProcessedContent{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return ProcessedContent{}, fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return ProcessedContent{}, fmt.Errorf("unexpected content type %s", mediaType)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxArticleSize), contentType)
+	if err != nil {
+		return ProcessedContent{}, fmt.Errorf("failed to decode page: %w", err)
+	}
+	doc, err := html.Parse(body)
+	if err != nil {
+		return ProcessedContent{}, fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	return ExtractArticle(doc, resp.Request.URL)
+}
+
+// ExtractArticle находит в документе блок с основным текстом по упрощенному
+// алгоритму Readability: абзацы начисляют очки родителю и деду, очки
+// корректируются по class/id и плотности ссылок, побеждает блок с максимумом.
+func ExtractArticle(doc *html.Node, base *url.URL) (ProcessedContent, error) {
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && (articleNoiseTags[node.DataAtom] || isNegativeBlock(node)) {
+			return
+		}
+		if node.Type == html.ElementNode && (node.DataAtom == atom.P || node.DataAtom == atom.Pre) {
+			text := strings.TrimSpace(nodeText(node))
+			if len([]rune(text)) >= minParagraphLength {
+				score := 1 + float64(strings.Count(text, ",")) + min(float64(len([]rune(text)))/100, 3)
+				if parent := node.Parent; parent != nil {
+					if _, ok := scores[parent]; !ok {
+						scores[parent] = classWeight(parent)
+						candidates = append(candidates, parent)
+					}
+					scores[parent] += score
+					if grandparent := parent.Parent; grandparent != nil && grandparent.Type == html.ElementNode {
+						if _, ok := scores[grandparent]; !ok {
+							scores[grandparent] = classWeight(grandparent)
+							candidates = append(candidates, grandparent)
+						}
+						scores[grandparent] += score / 2
+					}
+				}
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	var best *html.Node
+	var bestScore float64
+	for _, candidate := range candidates {
+		score := scores[candidate] * (1 - linkDensity(candidate))
+		if best == nil || score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	if best == nil {
+		return ProcessedContent{}, ErrArticleNotFound
+	}
+
+	var rendered strings.Builder
+	renderArticle(&rendered, best, base)
+	processed := ProcessContent(rendered.String())
+	if len([]rune(processed.Text)) < minArticleTextLength {
+		return ProcessedContent{}, ErrArticleNotFound
+	}
+	return processed, nil
+}
+
+func classWeight(node *html.Node) float64 {
+	var weight float64
+	for _, attr := range node.Attr {
+		if attr.Key != "class" && attr.Key != "id" {
+			continue
+		}
+		if positiveCandidate.MatchString(attr.Val) {
+			weight += 25
+		}
+		if negativeCandidate.MatchString(attr.Val) {
+			weight -= 25
+		}
+	}
+	if node.DataAtom == atom.Article || node.DataAtom == atom.Main {
+		weight += 10
+	}
+	return weight
+}
+
+// isNegativeBlock отсекает блоки комментариев, меню и т.п., у которых нет
+// признаков основного содержимого.
+func isNegativeBlock(node *html.Node) bool {
+	if node.DataAtom == atom.Body || node.DataAtom == atom.Html || node.DataAtom == atom.Article {
+		return false
+	}
+	for _, attr := range node.Attr {
+		if (attr.Key == "class" || attr.Key == "id") &&
+			negativeCandidate.MatchString(attr.Val) && !positiveCandidate.MatchString(attr.Val) {
+			return true
+		}
+	}
+	return false
+}
+
+func linkDensity(node *html.Node) float64 {
+	textLength := len([]rune(nodeText(node)))
+	if textLength == 0 {
+		return 1
+	}
+	var linkLength int
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			linkLength += len([]rune(nodeText(n)))
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return float64(linkLength) / float64(textLength)
+}
+
+func nodeText(node *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			return
+		}
+		if n.Type == html.ElementNode && articleNoiseTags[n.DataAtom] {
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return b.String()
+}
+
+// renderArticle выводит HTML выбранного блока без служебных и отрицательных
+// вложенных блоков, разрешая относительные ссылки. Окончательная очистка
+// выполняется в ProcessContent.
+func renderArticle(b *strings.Builder, node *html.Node, base *url.URL) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		switch {
+		case child.Type == html.TextNode:
+			b.WriteString(html.EscapeString(child.Data))
+		case child.Type != html.ElementNode:
+		case articleNoiseTags[child.DataAtom] || isNegativeBlock(child):
+		case child.DataAtom == atom.Br || child.DataAtom == atom.Hr || child.DataAtom == atom.Img:
+			b.WriteString("<" + child.Data + ">")
+		default:
+			b.WriteString("<" + child.Data)
+			for _, attr := range child.Attr {
+				if attr.Key != "href" {
+					continue
+				}
+				href := attr.Val
+				if ref, err := url.Parse(strings.TrimSpace(href)); err == nil && base != nil {
+					href = base.ResolveReference(ref).String()
+				}
+				b.WriteString(` href="` + html.EscapeString(href) + `"`)
+			}
+			b.WriteString(">")
+			renderArticle(b, child, base)
+			b.WriteString("</" + child.Data + ">")
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveFixture(t *testing.T, name, contentType string) *httptest.Server {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestArticleExtractor_Extract(t *testing.T) {
+	server := serveFixture(t, "article.html", "text/html; charset=utf-8")
+
+	article, err := NewArticleExtractor(loopbackGuard(t)).Extract(context.Background(), server.URL+"/news/electrobus")
+	require.NoError(t, err)
+
+	assert.Contains(t, article.Text, "сорок новых электробусов")
+	assert.Contains(t, article.Text, "запаса хода хватает на весь рабочий день")
+	assert.NotContains(t, article.Text, "Главная")
+	assert.NotContains(t, article.Text, "Курс рубля")
+	assert.NotContains(t, article.Text, "Отличная новость")
+	assert.NotContains(t, article.Text, "Все права защищены")
+	assert.NotContains(t, article.HTML, "<script")
+	assert.Contains(t, article.HTML, `href="`+server.URL+`/transport/plan"`)
+	assert.Greater(t, article.WordCount, 60)
+	assert.Equal(t, 1, article.ReadingMinutes)
+}
+
+func TestArticleExtractor_Teaser(t *testing.T) {
+	server := serveFixture(t, "teaser.html", "text/html; charset=utf-8")
+
+	_, err := NewArticleExtractor(loopbackGuard(t)).Extract(context.Background(), server.URL)
+	assert.ErrorIs(t, err, ErrArticleNotFound)
+}
+
+func TestArticleExtractor_NotHTML(t *testing.T) {
+	server := serveFixture(t, "article.html", "application/pdf")
+
+	_, err := NewArticleExtractor(loopbackGuard(t)).Extract(context.Background(), server.URL)
+	assert.Error(t, err)
+}
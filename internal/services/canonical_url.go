@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var trackingParams = map[string]bool{
+	"fbclid":    true,
+	"gclid":     true,
+	"yclid":     true,
+	"dclid":     true,
+	"msclkid":   true,
+	"mc_cid":    true,
+	"mc_eid":    true,
+	"_openstat": true,
+	"_ga":       true,
+	"igshid":    true,
+}
+
+// CanonicalizeURL приводит ссылку к виду, по которому сравниваются новости и
+// источники: схема https, хост в нижнем регистре без www и порта по умолчанию,
+// путь без завершающего слеша, параметры без меток отслеживания (utm_* и т.п.),
+// отсортированные по имени, и без фрагмента. Порядок значений повторяющегося
+// параметра сохраняется: для сервера он может быть значимым.
+func CanonicalizeURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("invalid url %q: %w", raw, err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("url %q has no host", raw)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimSuffix(host, ".")
+	host = strings.TrimPrefix(host, "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	path := u.EscapedPath()
+	for strings.HasSuffix(path, "/") {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	query := u.Query()
+	for key := range query {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "utm_") || trackingParams[lower] {
+			query.Del(key)
+		}
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			params = append(params, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+
+	canonical := "https://" + host + path
+	if len(params) > 0 {
+		canonical += "?" + strings.Join(params, "&")
+	}
+	return canonical, nil
+}
+
+// canonicalOrRaw используется при разборе лент: ссылки, которые не удалось
+// разобрать, сохраняются как есть, чтобы не терять новость.
+func canonicalOrRaw(raw string) string {
+	canonical, err := CanonicalizeURL(raw)
+	if err != nil {
+		return strings.TrimSpace(raw)
+	}
+	return canonical
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalizeURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{
+			name:     "tracking parameters and fragment",
+			raw:      "https://example.com/news/1?utm_source=rss&utm_medium=feed&id=5#comments",
+			expected: "https://example.com/news/1?id=5",
+		},
+		{
+			name:     "scheme host and default port",
+			raw:      "HTTP://WWW.Example.COM:80/News/",
+			expected: "https://example.com/News",
+		},
+		{
+			name:     "custom port is kept",
+			raw:      "https://example.com:8443/feed.xml",
+			expected: "https://example.com:8443/feed.xml",
+		},
+		{
+			name:     "parameters are sorted",
+			raw:      "https://example.com/search?b=2&a=1&fbclid=xyz",
+			expected: "https://example.com/search?a=1&b=2",
+		},
+		{
+			name:     "repeated parameter keeps value order",
+			raw:      "https://example.com/list?b=1&a=2&a=1",
+			expected: "https://example.com/list?a=2&a=1&b=1",
+		},
+		{
+			name:     "root path",
+			raw:      "https://example.com/",
+			expected: "https://example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalizeURL(tt.raw)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestCanonicalizeURL_Invalid(t *testing.T) {
+	for _, raw := range []string{"ftp://example.com/file", "not a url", "https://"} {
+		_, err := CanonicalizeURL(raw)
+		assert.Error(t, err, raw)
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+var xmlEncodingAttr = regexp.MustCompile(`^(\s*<\?xml[^>]*?\sencoding\s*=\s*)("[^"]*"|'[^']*')`)
+
+// NormalizeEncoding проверяет название кодировки и возвращает его
+// каноническую форму (например, "cp1251" -> "windows-1251").
+func NormalizeEncoding(label string) (string, error) {
+	enc, name := charset.Lookup(strings.TrimSpace(label))
+	if enc == nil {
+		return "", fmt.Errorf("unknown encoding %q", label)
+	}
+	return name, nil
+}
+
+// DecodeFeedBody перекодирует ленту в UTF-8 и возвращает название исходной
+// кодировки. Кодировка берется из override, если он задан, иначе из BOM,
+// заголовка Content-Type, XML-пролога и, если объявленной кодировке
+// противоречат сами байты, определяется по содержимому. Кодировка в прологе
+// заменяется на UTF-8, чтобы парсер не перекодировал текст повторно.
+func DecodeFeedBody(body []byte, contentType, override string) ([]byte, string, error) {
+	name := detectFeedEncoding(body, contentType, override)
+	if name == "utf-8" {
+		body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+		return markUTF8(body), name, nil
+	}
+
+	enc, _ := charset.Lookup(name)
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, name, fmt.Errorf("failed to decode feed from %s: %w", name, err)
+	}
+	return markUTF8(decoded), name, nil
+}
+
+func detectFeedEncoding(body []byte, contentType, override string) string {
+	if override != "" {
+		if name, err := NormalizeEncoding(override); err == nil {
+			return name
+		}
+	}
+
+	switch {
+	case bytes.HasPrefix(body, []byte("\xef\xbb\xbf")):
+		return "utf-8"
+	case bytes.HasPrefix(body, []byte("\xfe\xff")):
+		return "utf-16be"
+	case bytes.HasPrefix(body, []byte("\xff\xfe")):
+		return "utf-16le"
+	}
+
+	declared := ""
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		declared = params["charset"]
+	}
+	if declared == "" {
+		if match := xmlEncodingAttr.FindSubmatch(body); match != nil {
+			declared = strings.Trim(string(match[2]), `"'`)
+		}
+	}
+	if declared != "" {
+		if name, err := NormalizeEncoding(declared); err == nil {
+			// Ленты часто объявляют UTF-8, отдавая windows-1251
+			if name != "utf-8" || utf8.Valid(body) {
+				return name
+			}
+		}
+	}
+
+	if utf8.Valid(body) {
+		return "utf-8"
+	}
+	if name, ok := sniffCyrillicEncoding(body); ok {
+		return name
+	}
+	_, name, _ := charset.DetermineEncoding(body, contentType)
+	return name
+}
+
+// sniffCyrillicEncoding определяет, похож ли текст на кириллицу в
+// однобайтовой кодировке, и различает windows-1251 и KOI8-R. В кириллическом
+// тексте буквы 0xC0-0xFF идут подряд целыми словами, а в западноевропейских
+// кодировках это отдельные буквы с диакритикой среди латиницы. Строчные буквы
+// в windows-1251 занимают 0xE0-0xFF, в KOI8-R - 0xC0-0xDF, а в обычном тексте
+// строчных намного больше, чем заглавных.
+func sniffCyrillicEncoding(body []byte) (string, bool) {
+	var upperHalf, lowerHalf, paired int
+	for i, b := range body {
+		if b < 0xC0 {
+			continue
+		}
+		if b >= 0xE0 {
+			upperHalf++
+		} else {
+			lowerHalf++
+		}
+		if i > 0 && body[i-1] >= 0xC0 {
+			paired++
+		}
+	}
+	letters := upperHalf + lowerHalf
+	if letters < 4 || paired*2 < letters {
+		return "", false
+	}
+	if lowerHalf > upperHalf {
+		return "koi8-r", true
+	}
+	return "windows-1251", true
+}
+
+func markUTF8(body []byte) []byte {
+	return xmlEncodingAttr.ReplaceAll(body, []byte(`${1}"UTF-8"`))
+}
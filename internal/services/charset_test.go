@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html/charset"
+)
+
+const cyrillicTitle = "Новости экономики и общества"
+
+func encodeFeed(t *testing.T, encoding, prologEncoding string) []byte {
+	t.Helper()
+	prolog := `<?xml version="1.0"?>`
+	if prologEncoding != "" {
+		prolog = `<?xml version="1.0" encoding="` + prologEncoding + `"?>`
+	}
+	feed := prolog + `<rss version="2.0"><channel><title>` + cyrillicTitle + `</title>` +
+		`<item><title>` + cyrillicTitle + `</title><link>https://example.com/1</link></item></channel></rss>`
+	enc, _ := charset.Lookup(encoding)
+	require.NotNil(t, enc)
+	encoded, err := enc.NewEncoder().String(feed)
+	require.NoError(t, err)
+	return []byte(encoded)
+}
+
+func parseTitle(t *testing.T, body []byte) string {
+	t.Helper()
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+	require.NoError(t, err)
+	return feed.Title
+}
+
+func TestDecodeFeedBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		override    string
+		want        string
+	}{
+		{"header charset", encodeFeed(t, "windows-1251", "windows-1251"), "application/rss+xml; charset=windows-1251", "", "windows-1251"},
+		{"xml prolog", encodeFeed(t, "koi8-r", "KOI8-R"), "application/rss+xml", "", "koi8-r"},
+		{"header lies about utf-8", encodeFeed(t, "windows-1251", ""), "text/xml; charset=utf-8", "", "windows-1251"},
+		{"sniffed koi8-r", encodeFeed(t, "koi8-r", ""), "", "", "koi8-r"},
+		{"override wins", encodeFeed(t, "koi8-r", "windows-1251"), "text/xml; charset=windows-1251", "koi8-r", "koi8-r"},
+		{"utf-8 with bom", append([]byte("\xef\xbb\xbf"), encodeFeed(t, "utf-8", "utf-8")...), "", "", "utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, encoding, err := DecodeFeedBody(tt.body, tt.contentType, tt.override)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, encoding)
+			assert.Equal(t, cyrillicTitle, parseTitle(t, decoded))
+		})
+	}
+}
+
+func TestDecodeFeedBody_LatinWithoutCharset(t *testing.T) {
+	const title = "Café, crème brûlée et déjà vu à Zürich"
+	feed := `<?xml version="1.0"?><rss version="2.0"><channel><title>` + title + `</title></channel></rss>`
+	enc, _ := charset.Lookup("iso-8859-1")
+	encoded, err := enc.NewEncoder().String(feed)
+	require.NoError(t, err)
+
+	decoded, encoding, err := DecodeFeedBody([]byte(encoded), "application/rss+xml", "")
+	require.NoError(t, err)
+	assert.Equal(t, "windows-1252", encoding)
+	assert.Equal(t, title, parseTitle(t, decoded))
+}
+
+func TestNormalizeEncoding(t *testing.T) {
+	name, err := NormalizeEncoding("CP1251")
+	require.NoError(t, err)
+	assert.Equal(t, "windows-1251", name)
+
+	_, err = NormalizeEncoding("martian")
+	assert.Error(t, err)
+}
+
+func TestRssParser_ParseURL_Encoding(t *testing.T) {
+	body := encodeFeed(t, "windows-1251", "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	parser := NewRssParser(1, loopbackGuard(t), FetchLimits{}, RetryPolicy{})
+	result, err := parser.ParseURL(context.Background(), server.URL, FetchOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, cyrillicTitle, result.Items[0].Title)
+
+	result, err = parser.ParseURL(context.Background(), server.URL, FetchOptions{Encoding: "koi8-r"})
+	require.NoError(t, err)
+	assert.NotEqual(t, cyrillicTitle, result.Items[0].Title)
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	maxTitleLength = 500
+	wordsPerMinute = 200
+)
+
+// ProcessedContent - результат обработки HTML-описания новости.
+type ProcessedContent struct {
+	HTML           string
+	Text           string
+	WordCount      int
+	ReadingMinutes int
+}
+
+// Разрешенные теги и их атрибуты. Остальные теги разворачиваются (остается
+// только их содержимое), а теги из droppedTags удаляются вместе с содержимым.
+var allowedTags = map[atom.Atom][]string{
+	atom.P:          nil,
+	atom.Br:         nil,
+	atom.B:          nil,
+	atom.Strong:     nil,
+	atom.I:          nil,
+	atom.Em:         nil,
+	atom.U:          nil,
+	atom.S:          nil,
+	atom.A:          {"href", "title"},
+	atom.Ul:         nil,
+	atom.Ol:         nil,
+	atom.Li:         nil,
+	atom.Blockquote: nil,
+	atom.Code:       nil,
+	atom.Pre:        nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+}
+
+var droppedTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Noscript: true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Form:     true,
+	atom.Img:      true,
+	atom.Svg:      true,
+	atom.Head:     true,
+	atom.Template: true,
+}
+
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Ul: true, atom.Ol: true,
+	atom.Blockquote: true, atom.Pre: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Tr: true, atom.Table: true, atom.Section: true,
+	atom.Article: true, atom.Header: true, atom.Footer: true, atom.Figure: true, atom.Hr: true,
+}
+
+// ProcessContent очищает HTML по списку разрешенных тегов, извлекает из него
+// простой текст и оценивает объем текста.
+func ProcessContent(raw string) ProcessedContent {
+	if strings.TrimSpace(raw) == "" {
+		return ProcessedContent{}
+	}
+
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(raw), body)
+	if err != nil {
+		text := normalizeText(raw)
+		return newProcessedContent(html.EscapeString(text), text)
+	}
+
+	var sanitized strings.Builder
+	var text strings.Builder
+	for _, node := range nodes {
+		sanitizeNode(&sanitized, node)
+		extractText(&text, node)
+	}
+
+	return newProcessedContent(strings.TrimSpace(sanitized.String()), normalizeText(text.String()))
+}
+
+func newProcessedContent(sanitized, text string) ProcessedContent {
+	words := len(strings.Fields(text))
+	minutes := 0
+	if words > 0 {
+		minutes = int(math.Ceil(float64(words) / wordsPerMinute))
+	}
+	return ProcessedContent{
+		HTML:           sanitized,
+		Text:           text,
+		WordCount:      words,
+		ReadingMinutes: minutes,
+	}
+}
+
+// TruncateTitle обрезает заголовок до длины колонки news_items.title.
+func TruncateTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	if utf8.RuneCountInString(title) <= maxTitleLength {
+		return title
+	}
+	runes := []rune(title)
+	return strings.TrimSpace(string(runes[:maxTitleLength-1])) + "…"
+}
+
+func sanitizeNode(b *strings.Builder, node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		b.WriteString(html.EscapeString(node.Data))
+		return
+	case html.ElementNode:
+	default:
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			sanitizeNode(b, child)
+		}
+		return
+	}
+
+	if droppedTags[node.DataAtom] {
+		return
+	}
+	attrs, allowed := allowedTags[node.DataAtom]
+	if !allowed {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			sanitizeNode(b, child)
+		}
+		return
+	}
+
+	b.WriteString("<" + node.Data)
+	for _, attr := range node.Attr {
+		if !slices.Contains(attrs, attr.Key) {
+			continue
+		}
+		if attr.Key == "href" && !isSafeLink(attr.Val) {
+			continue
+		}
+		b.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+	}
+	if node.DataAtom == atom.A {
+		b.WriteString(` rel="nofollow noopener" target="_blank"`)
+	}
+	b.WriteString(">")
+	if node.DataAtom == atom.Br {
+		return
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		sanitizeNode(b, child)
+	}
+	b.WriteString("</" + node.Data + ">")
+}
+
+func extractText(b *strings.Builder, node *html.Node) {
+	if node.Type == html.TextNode {
+		b.WriteString(node.Data)
+		return
+	}
+	if node.Type == html.ElementNode && droppedTags[node.DataAtom] {
+		return
+	}
+	block := node.Type == html.ElementNode && blockTags[node.DataAtom]
+	if block {
+		b.WriteString("\n")
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		extractText(b, child)
+	}
+	if block {
+		b.WriteString("\n")
+	}
+}
+
+// normalizeText схлопывает пробелы внутри строк и оставляет не больше одной
+// пустой строки между абзацами.
+func normalizeText(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" && (len(lines) == 0 || lines[len(lines)-1] == "") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func isSafeLink(value string) bool {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "http" || scheme == "https" || scheme == "mailto"
+}
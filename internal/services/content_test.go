@@ -0,0 +1,46 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessContent(t *testing.T) {
+	raw := `<div class="lead"><p>Первый &amp; главный абзац с <a href="https://example.com/a" onclick="track()">ссылкой</a>.</p>` +
+		`<script>alert("x")</script><img src="https://tracker.example.com/pixel.gif" width="1" height="1">` +
+		`<p>Второй <span style="color:red">абзац</span> <a href="javascript:alert(1)">тут</a></p></div>`
+
+	processed := ProcessContent(raw)
+
+	assert.Equal(t,
+		`<p>Первый &amp; главный абзац с <a href="https://example.com/a" rel="nofollow noopener" target="_blank">ссылкой</a>.</p>`+
+			`<p>Второй абзац <a rel="nofollow noopener" target="_blank">тут</a></p>`,
+		processed.HTML,
+	)
+	assert.Equal(t, "Первый & главный абзац с ссылкой.\n\nВторой абзац тут", processed.Text)
+	assert.Equal(t, 9, processed.WordCount)
+	assert.Equal(t, 1, processed.ReadingMinutes)
+}
+
+func TestProcessContent_PlainText(t *testing.T) {
+	processed := ProcessContent(strings.Repeat("слово ", 450))
+
+	assert.Equal(t, 450, processed.WordCount)
+	assert.Equal(t, 3, processed.ReadingMinutes)
+	assert.NotContains(t, processed.Text, "  ")
+}
+
+func TestProcessContent_Empty(t *testing.T) {
+	assert.Equal(t, ProcessedContent{}, ProcessContent("  "))
+}
+
+func TestTruncateTitle(t *testing.T) {
+	assert.Equal(t, "Короткий заголовок", TruncateTitle("  Короткий\n заголовок "))
+
+	long := TruncateTitle(strings.Repeat("я", 600))
+	assert.Equal(t, maxTitleLength, utf8.RuneCountInString(long))
+	assert.True(t, strings.HasSuffix(long, "…"))
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/SANEKNAYMCHIK/newsBot/internal/repositories"
+)
+
+// ErrRecipientUnavailable возвращается отправителем, если доставка пользователю
+// невозможна в принципе (бот заблокирован, чат удален) и повторять ее бессмысленно.
+var ErrRecipientUnavailable = errors.New("recipient is unavailable")
+
+type NewsSender interface {
+	SendNews(ctx context.Context, delivery models.PendingDelivery) error
+}
+
+type DeliveryService struct {
+	deliveryRepo repositories.DeliveryRepository
+	sender       NewsSender
+
+	pollInterval time.Duration
+	batchSize    int
+	maxAttempts  int
+	staleAfter   time.Duration
+	sendDelay    time.Duration
+}
+
+func NewDeliveryService(
+	deliveryRepo repositories.DeliveryRepository,
+	sender NewsSender,
+	pollInterval time.Duration,
+	batchSize int,
+	maxAttempts int,
+) *DeliveryService {
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	return &DeliveryService{
+		deliveryRepo: deliveryRepo,
+		sender:       sender,
+		pollInterval: pollInterval,
+		batchSize:    batchSize,
+		maxAttempts:  maxAttempts,
+		staleAfter:   5 * time.Minute,
+		sendDelay:    50 * time.Millisecond,
+	}
+}
+
+func (s *DeliveryService) Start(ctx context.Context) {
+	log.Printf("Starting DeliveryService with interval %v", s.pollInterval)
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("DeliveryService stopping...")
+			return
+		case <-ticker.C:
+			for {
+				sent, err := s.Dispatch(ctx)
+				if err != nil {
+					log.Printf("Delivery dispatch failed: %v", err)
+					break
+				}
+				if sent < s.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Dispatch забирает очередную пачку ожидающих доставок и отправляет их.
+// Возвращает количество обработанных доставок.
+func (s *DeliveryService) Dispatch(ctx context.Context) (int, error) {
+	deliveries, err := s.deliveryRepo.ClaimPending(ctx, s.batchSize, s.staleAfter)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		s.deliver(ctx, delivery)
+		time.Sleep(s.sendDelay)
+	}
+
+	return len(deliveries), nil
+}
+
+func (s *DeliveryService) deliver(ctx context.Context, delivery models.PendingDelivery) {
+	err := s.sender.SendNews(ctx, delivery)
+	if err == nil {
+		if err := s.deliveryRepo.MarkSent(ctx, delivery.DeliveryID); err != nil {
+			log.Printf("Failed to mark delivery %d as sent: %v", delivery.DeliveryID, err)
+		}
+		return
+	}
+
+	if errors.Is(err, ErrRecipientUnavailable) || delivery.Attempts >= s.maxAttempts {
+		log.Printf("Delivery %d to user %d failed permanently: %v", delivery.DeliveryID, delivery.UserID, err)
+		if err := s.deliveryRepo.MarkFailed(ctx, delivery.DeliveryID, err.Error()); err != nil {
+			log.Printf("Failed to mark delivery %d as failed: %v", delivery.DeliveryID, err)
+		}
+		return
+	}
+
+	nextAttempt := time.Now().Add(retryDelay(delivery.Attempts))
+	log.Printf("Delivery %d to user %d failed (attempt %d), retry at %v: %v",
+		delivery.DeliveryID, delivery.UserID, delivery.Attempts, nextAttempt, err)
+	if err := s.deliveryRepo.MarkRetry(ctx, delivery.DeliveryID, nextAttempt, err.Error()); err != nil {
+		log.Printf("Failed to reschedule delivery %d: %v", delivery.DeliveryID, err)
+	}
+}
+
+func retryDelay(attempts int) time.Duration {
+	delay := time.Duration(attempts*attempts) * 30 * time.Second
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
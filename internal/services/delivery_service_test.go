@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDeliveryRepository struct {
+	mock.Mock
+}
+
+func (m *MockDeliveryRepository) EnqueueForNews(ctx context.Context, newsIDs []int64) (int64, error) {
+	args := m.Called(ctx, newsIDs)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockDeliveryRepository) RequeueUpdated(ctx context.Context, newsIDs []int64) (int64, error) {
+	args := m.Called(ctx, newsIDs)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockDeliveryRepository) ClaimPending(ctx context.Context, limit int, staleAfter time.Duration) ([]models.PendingDelivery, error) {
+	args := m.Called(ctx, limit, staleAfter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PendingDelivery), args.Error(1)
+}
+
+func (m *MockDeliveryRepository) MarkSent(ctx context.Context, deliveryID int64) error {
+	args := m.Called(ctx, deliveryID)
+	return args.Error(0)
+}
+
+func (m *MockDeliveryRepository) MarkRetry(ctx context.Context, deliveryID int64, nextAttemptAt time.Time, lastError string) error {
+	args := m.Called(ctx, deliveryID, nextAttemptAt, lastError)
+	return args.Error(0)
+}
+
+func (m *MockDeliveryRepository) MarkFailed(ctx context.Context, deliveryID int64, lastError string) error {
+	args := m.Called(ctx, deliveryID, lastError)
+	return args.Error(0)
+}
+
+func (m *MockDeliveryRepository) ClaimPendingForUser(ctx context.Context, userID int64, limit int, maxAttempts int) ([]models.PendingDelivery, error) {
+	args := m.Called(ctx, userID, limit, maxAttempts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PendingDelivery), args.Error(1)
+}
+
+func (m *MockDeliveryRepository) MarkSentMany(ctx context.Context, deliveryIDs []int64) error {
+	args := m.Called(ctx, deliveryIDs)
+	return args.Error(0)
+}
+
+func (m *MockDeliveryRepository) ReleaseMany(ctx context.Context, deliveryIDs []int64, lastError string) error {
+	args := m.Called(ctx, deliveryIDs, lastError)
+	return args.Error(0)
+}
+
+func (m *MockDeliveryRepository) MarkFailedMany(ctx context.Context, deliveryIDs []int64, lastError string) error {
+	args := m.Called(ctx, deliveryIDs, lastError)
+	return args.Error(0)
+}
+
+type MockNewsSender struct {
+	mock.Mock
+}
+
+func (m *MockNewsSender) SendNews(ctx context.Context, delivery models.PendingDelivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+func newTestDeliveryService(repo *MockDeliveryRepository, sender *MockNewsSender) *DeliveryService {
+	service := NewDeliveryService(repo, sender, time.Second, 10, 3)
+	service.sendDelay = 0
+	return service
+}
+
+func TestDeliveryService_Dispatch_Sent(t *testing.T) {
+	mockRepo := new(MockDeliveryRepository)
+	mockSender := new(MockNewsSender)
+	service := newTestDeliveryService(mockRepo, mockSender)
+
+	ctx := context.Background()
+	delivery := models.PendingDelivery{DeliveryID: 1, Attempts: 1, UserID: 2, ChatID: 100}
+
+	mockRepo.On("ClaimPending", ctx, 10, 5*time.Minute).Return([]models.PendingDelivery{delivery}, nil)
+	mockSender.On("SendNews", ctx, delivery).Return(nil)
+	mockRepo.On("MarkSent", ctx, int64(1)).Return(nil)
+
+	processed, err := service.Dispatch(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	mockRepo.AssertNotCalled(t, "MarkRetry")
+	mockRepo.AssertExpectations(t)
+	mockSender.AssertExpectations(t)
+}
+
+func TestDeliveryService_Dispatch_RetryOnTransientError(t *testing.T) {
+	mockRepo := new(MockDeliveryRepository)
+	mockSender := new(MockNewsSender)
+	service := newTestDeliveryService(mockRepo, mockSender)
+
+	ctx := context.Background()
+	delivery := models.PendingDelivery{DeliveryID: 1, Attempts: 1, UserID: 2, ChatID: 100}
+
+	mockRepo.On("ClaimPending", ctx, 10, 5*time.Minute).Return([]models.PendingDelivery{delivery}, nil)
+	mockSender.On("SendNews", ctx, delivery).Return(assert.AnError)
+	mockRepo.On("MarkRetry", ctx, int64(1), mock.MatchedBy(func(next time.Time) bool {
+		return next.After(time.Now())
+	}), assert.AnError.Error()).Return(nil)
+
+	_, err := service.Dispatch(ctx)
+
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "MarkSent")
+	mockRepo.AssertNotCalled(t, "MarkFailed")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeliveryService_Dispatch_GivesUpAfterMaxAttempts(t *testing.T) {
+	mockRepo := new(MockDeliveryRepository)
+	mockSender := new(MockNewsSender)
+	service := newTestDeliveryService(mockRepo, mockSender)
+
+	ctx := context.Background()
+	delivery := models.PendingDelivery{DeliveryID: 1, Attempts: 3, UserID: 2, ChatID: 100}
+
+	mockRepo.On("ClaimPending", ctx, 10, 5*time.Minute).Return([]models.PendingDelivery{delivery}, nil)
+	mockSender.On("SendNews", ctx, delivery).Return(assert.AnError)
+	mockRepo.On("MarkFailed", ctx, int64(1), assert.AnError.Error()).Return(nil)
+
+	_, err := service.Dispatch(ctx)
+
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "MarkRetry")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeliveryService_Dispatch_RecipientUnavailable(t *testing.T) {
+	mockRepo := new(MockDeliveryRepository)
+	mockSender := new(MockNewsSender)
+	service := newTestDeliveryService(mockRepo, mockSender)
+
+	ctx := context.Background()
+	delivery := models.PendingDelivery{DeliveryID: 1, Attempts: 1, UserID: 2, ChatID: 100}
+	sendErr := fmt.Errorf("%w: bot was blocked by the user", ErrRecipientUnavailable)
+
+	mockRepo.On("ClaimPending", ctx, 10, 5*time.Minute).Return([]models.PendingDelivery{delivery}, nil)
+	mockSender.On("SendNews", ctx, delivery).Return(sendErr)
+	mockRepo.On("MarkFailed", ctx, int64(1), sendErr.Error()).Return(nil)
+
+	_, err := service.Dispatch(ctx)
+
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "MarkRetry")
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/SANEKNAYMCHIK/newsBot/internal/repositories"
+)
+
+// DigestSender отправляет дайджест и возвращает ID новостей, которые успели
+// уйти пользователю, даже если отправка прервалась с ошибкой.
+type DigestSender interface {
+	SendDigest(ctx context.Context, digest models.Digest) ([]int64, error)
+}
+
+type DigestService struct {
+	settingsRepo repositories.SettingsRepository
+	deliveryRepo repositories.DeliveryRepository
+	sender       DigestSender
+
+	interval    time.Duration
+	maxItems    int
+	maxAttempts int
+	sendDelay   time.Duration
+}
+
+func NewDigestService(
+	settingsRepo repositories.SettingsRepository,
+	deliveryRepo repositories.DeliveryRepository,
+	sender DigestSender,
+	interval time.Duration,
+	maxItems int,
+	maxAttempts int,
+) *DigestService {
+	if maxItems <= 0 {
+		maxItems = 100
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	return &DigestService{
+		settingsRepo: settingsRepo,
+		deliveryRepo: deliveryRepo,
+		sender:       sender,
+		interval:     interval,
+		maxItems:     maxItems,
+		maxAttempts:  maxAttempts,
+		sendDelay:    50 * time.Millisecond,
+	}
+}
+
+func (s *DigestService) Start(ctx context.Context) {
+	log.Printf("Starting DigestService with interval %v", s.interval)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("DigestService stopping...")
+			return
+		case <-ticker.C:
+			if err := s.RunDue(ctx); err != nil {
+				log.Printf("Digest run failed: %v", err)
+			}
+		}
+	}
+}
+
+func (s *DigestService) RunDue(ctx context.Context) error {
+	now := time.Now()
+	due, err := s.settingsRepo.GetDueDigests(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for i := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.sendDigest(ctx, &due[i], now)
+		time.Sleep(s.sendDelay)
+	}
+	return nil
+}
+
+func (s *DigestService) sendDigest(ctx context.Context, settings *models.UserSettings, now time.Time) {
+	next := NextDigestTime(settings, now)
+
+	deliveries, err := s.deliveryRepo.ClaimPendingForUser(ctx, settings.UserID, s.maxItems, s.maxAttempts)
+	if err != nil {
+		log.Printf("Failed to collect digest for user %d: %v", settings.UserID, err)
+		return
+	}
+	if len(deliveries) == 0 {
+		if err := s.settingsRepo.ScheduleNextDigest(ctx, settings.UserID, nil, next); err != nil {
+			log.Printf("Failed to reschedule digest for user %d: %v", settings.UserID, err)
+		}
+		return
+	}
+
+	digest := BuildDigest(deliveries, settings.GroupBy)
+	digest.Since = settings.LastDigestAt
+	sentNewsIDs, sendErr := s.sender.SendDigest(ctx, digest)
+
+	// Новости из уже отправленных частей дайджеста отмечаются отправленными,
+	// чтобы при повторе не прийти второй раз
+	sentNews := make(map[int64]bool, len(sentNewsIDs))
+	for _, id := range sentNewsIDs {
+		sentNews[id] = true
+	}
+	var sentIDs, retryIDs, failedIDs []int64
+	attempts := 0
+	for _, d := range deliveries {
+		switch {
+		case sendErr == nil || sentNews[d.News.ID]:
+			sentIDs = append(sentIDs, d.DeliveryID)
+		case errors.Is(sendErr, ErrRecipientUnavailable) || d.Attempts >= s.maxAttempts:
+			failedIDs = append(failedIDs, d.DeliveryID)
+		default:
+			retryIDs = append(retryIDs, d.DeliveryID)
+			attempts = max(attempts, d.Attempts)
+		}
+	}
+	if len(sentIDs) > 0 {
+		if err := s.deliveryRepo.MarkSentMany(ctx, sentIDs); err != nil {
+			log.Printf("Failed to mark digest deliveries for user %d: %v", settings.UserID, err)
+		}
+	}
+
+	if sendErr != nil {
+		log.Printf("Failed to send digest to user %d: %v", settings.UserID, sendErr)
+		// Пользователь заблокировал бота или попытки исчерпаны: повторять бессмысленно
+		if len(failedIDs) > 0 {
+			if err := s.deliveryRepo.MarkFailedMany(ctx, failedIDs, sendErr.Error()); err != nil {
+				log.Printf("Failed to mark digest deliveries for user %d as failed: %v", settings.UserID, err)
+			}
+		}
+		if len(retryIDs) > 0 {
+			if err := s.deliveryRepo.ReleaseMany(ctx, retryIDs, sendErr.Error()); err != nil {
+				log.Printf("Failed to release digest deliveries for user %d: %v", settings.UserID, err)
+			}
+			// Повтор откладывается так же, как при мгновенной доставке
+			retryAt := now.Add(retryDelay(attempts)).UTC()
+			next = &retryAt
+		}
+		if err := s.settingsRepo.ScheduleNextDigest(ctx, settings.UserID, nil, next); err != nil {
+			log.Printf("Failed to reschedule digest for user %d: %v", settings.UserID, err)
+		}
+		return
+	}
+
+	if err := s.settingsRepo.ScheduleNextDigest(ctx, settings.UserID, &now, next); err != nil {
+		log.Printf("Failed to reschedule digest for user %d: %v", settings.UserID, err)
+	}
+	log.Printf("Sent digest with %d items to user %d", len(deliveries), settings.UserID)
+}
+
+// BuildDigest группирует новости по источнику или категории.
+// Группы сортируются по имени, новости внутри группы — от новых к старым.
+func BuildDigest(deliveries []models.PendingDelivery, groupBy string) models.Digest {
+	digest := models.Digest{}
+	if len(deliveries) == 0 {
+		return digest
+	}
+	digest.ChatID = deliveries[0].ChatID
+
+	groups := make(map[string][]models.NewsItem)
+	for _, d := range deliveries {
+		name := d.SourceName
+		if groupBy == models.DigestGroupByCategory {
+			name = d.CategoryName
+			if name == "" {
+				name = "Без категории"
+			}
+		}
+		groups[name] = append(groups[name], d.News)
+	}
+
+	for name, news := range groups {
+		sort.Slice(news, func(i, j int) bool {
+			return news[i].PublishedAt.After(news[j].PublishedAt)
+		})
+		digest.Groups = append(digest.Groups, models.DigestGroup{Name: name, News: news})
+	}
+	sort.Slice(digest.Groups, func(i, j int) bool {
+		return digest.Groups[i].Name < digest.Groups[j].Name
+	})
+
+	return digest
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSettingsRepository struct {
+	mock.Mock
+}
+
+func (m *MockSettingsRepository) GetByUserID(ctx context.Context, userID int64) (*models.UserSettings, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserSettings), args.Error(1)
+}
+
+func (m *MockSettingsRepository) Upsert(ctx context.Context, settings *models.UserSettings) error {
+	args := m.Called(ctx, settings)
+	return args.Error(0)
+}
+
+func (m *MockSettingsRepository) GetDueDigests(ctx context.Context, now time.Time) ([]models.UserSettings, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]models.UserSettings), args.Error(1)
+}
+
+func (m *MockSettingsRepository) ScheduleNextDigest(ctx context.Context, userID int64, sentAt *time.Time, nextDigestAt *time.Time) error {
+	args := m.Called(ctx, userID, sentAt, nextDigestAt)
+	return args.Error(0)
+}
+
+type MockDigestSender struct {
+	mock.Mock
+}
+
+func (m *MockDigestSender) SendDigest(ctx context.Context, digest models.Digest) ([]int64, error) {
+	args := m.Called(ctx, digest)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func digestDeliveries() []models.PendingDelivery {
+	return []models.PendingDelivery{
+		{DeliveryID: 1, UserID: 7, ChatID: 70, News: models.NewsItem{ID: 11, Title: "a"}, SourceName: "s"},
+		{DeliveryID: 2, UserID: 7, ChatID: 70, News: models.NewsItem{ID: 12, Title: "b"}, SourceName: "s"},
+	}
+}
+
+func TestDigestService_PartialSendReleasesOnlyUnsentItems(t *testing.T) {
+	settingsRepo := new(MockSettingsRepository)
+	deliveryRepo := new(MockDeliveryRepository)
+	sender := new(MockDigestSender)
+	service := NewDigestService(settingsRepo, deliveryRepo, sender, time.Minute, 10, 3)
+
+	settings := &models.UserSettings{UserID: 7, DeliveryMode: models.DeliveryModeHourly}
+	deliveryRepo.On("ClaimPendingForUser", mock.Anything, int64(7), 10, 3).Return(digestDeliveries(), nil)
+	sender.On("SendDigest", mock.Anything, mock.Anything).Return([]int64{11}, errors.New("timeout"))
+	deliveryRepo.On("MarkSentMany", mock.Anything, []int64{1}).Return(nil)
+	deliveryRepo.On("ReleaseMany", mock.Anything, []int64{2}, "timeout").Return(nil)
+	now := time.Now()
+	retryAt := now.Add(retryDelay(0)).UTC()
+	settingsRepo.On("ScheduleNextDigest", mock.Anything, int64(7), (*time.Time)(nil), &retryAt).Return(nil)
+
+	service.sendDigest(context.Background(), settings, now)
+
+	deliveryRepo.AssertExpectations(t)
+	settingsRepo.AssertExpectations(t)
+	deliveryRepo.AssertNotCalled(t, "MarkFailedMany", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDigestService_ExhaustedAttemptsFailDeliveries(t *testing.T) {
+	settingsRepo := new(MockSettingsRepository)
+	deliveryRepo := new(MockDeliveryRepository)
+	sender := new(MockDigestSender)
+	service := NewDigestService(settingsRepo, deliveryRepo, sender, time.Minute, 10, 3)
+
+	settings := &models.UserSettings{UserID: 7, DeliveryMode: models.DeliveryModeHourly, Timezone: "UTC"}
+	deliveries := digestDeliveries()
+	deliveries[0].Attempts = 3
+	deliveries[1].Attempts = 2
+	deliveryRepo.On("ClaimPendingForUser", mock.Anything, int64(7), 10, 3).Return(deliveries, nil)
+	sender.On("SendDigest", mock.Anything, mock.Anything).Return(nil, errors.New("timeout"))
+	deliveryRepo.On("MarkFailedMany", mock.Anything, []int64{1}, "timeout").Return(nil)
+	deliveryRepo.On("ReleaseMany", mock.Anything, []int64{2}, "timeout").Return(nil)
+	now := time.Now()
+	retryAt := now.Add(retryDelay(2)).UTC()
+	settingsRepo.On("ScheduleNextDigest", mock.Anything, int64(7), (*time.Time)(nil), &retryAt).Return(nil)
+
+	service.sendDigest(context.Background(), settings, now)
+
+	deliveryRepo.AssertExpectations(t)
+	settingsRepo.AssertExpectations(t)
+}
+
+func TestDigestService_RecipientUnavailableFailsDeliveries(t *testing.T) {
+	settingsRepo := new(MockSettingsRepository)
+	deliveryRepo := new(MockDeliveryRepository)
+	sender := new(MockDigestSender)
+	service := NewDigestService(settingsRepo, deliveryRepo, sender, time.Minute, 10, 3)
+
+	settings := &models.UserSettings{UserID: 7, DeliveryMode: models.DeliveryModeHourly}
+	deliveryRepo.On("ClaimPendingForUser", mock.Anything, int64(7), 10, 3).Return(digestDeliveries(), nil)
+	sender.On("SendDigest", mock.Anything, mock.Anything).Return(nil, ErrRecipientUnavailable)
+	deliveryRepo.On("MarkFailedMany", mock.Anything, []int64{1, 2}, ErrRecipientUnavailable.Error()).Return(nil)
+	settingsRepo.On("ScheduleNextDigest", mock.Anything, int64(7), (*time.Time)(nil), mock.Anything).Return(nil)
+
+	service.sendDigest(context.Background(), settings, time.Now())
+
+	deliveryRepo.AssertExpectations(t)
+	settingsRepo.AssertExpectations(t)
+	deliveryRepo.AssertNotCalled(t, "ReleaseMany", mock.Anything, mock.Anything, mock.Anything)
+}
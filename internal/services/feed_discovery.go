@@ -0,0 +1,257 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	maxDiscoveryPageSize = 5 << 20
+	maxFeedCandidates    = 5
+	// discoveryTimeout ограничивает весь поиск, включая проверку всех кандидатов
+	discoveryTimeout = 20 * time.Second
+)
+
+var ErrFeedNotFound = errors.New("no RSS or Atom feed found at this URL")
+
+// Типичные пути лент, которые проверяются, если страница не ссылается на
+// ленту через <link rel="alternate">.
+var commonFeedPaths = []string{"/feed", "/rss", "/rss.xml", "/feed.xml", "/atom.xml", "/index.xml"}
+
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":  true,
+	"application/atom+xml": true,
+	"application/feed+xml": true,
+	"application/xml":      true,
+	"text/xml":             true,
+}
+
+// FeedChoiceError возвращается, когда по адресу сайта найдено несколько лент
+// и пользователь должен выбрать одну из них.
+type FeedChoiceError struct {
+	Candidates []models.FeedCandidate
+}
+
+func (e *FeedChoiceError) Error() string {
+	return fmt.Sprintf("found %d feeds at this URL, choose one", len(e.Candidates))
+}
+
+type FeedDiscoverer struct {
+	client  *http.Client
+	hosts   *hostLimiter
+	timeout time.Duration
+	maxBody int64
+}
+
+// NewFeedDiscoverer создает поиск лент. Если передан парсер, запросы к хостам
+// ограничиваются тем же hostLimiter, а размер ответа - тем же MaxBodyBytes.
+func NewFeedDiscoverer(guard *NetworkGuard, parser *RssParser) *FeedDiscoverer {
+	hosts := newHostLimiter(FetchLimits{}.withDefaults().MaxPerHost)
+	maxBody := int64(maxDiscoveryPageSize)
+	if parser != nil {
+		hosts = parser.hosts
+		maxBody = parser.limits.MaxBodyBytes
+	}
+	return &FeedDiscoverer{
+		client:  guardOrDefault(guard).Client(10*time.Second, 10*time.Second, nil),
+		hosts:   hosts,
+		timeout: discoveryTimeout,
+		maxBody: maxBody,
+	}
+}
+
+// discoveredFeed - найденная лента вместе с уже загруженным телом, чтобы при
+// добавлении источника не скачивать ее повторно.
+type discoveredFeed struct {
+	models.FeedCandidate
+	body        []byte
+	contentType string
+}
+
+// Discover возвращает ленты по адресу: сам адрес, если это лента, иначе ленты,
+// на которые ссылается HTML-страница, или найденные по типичным путям.
+func (d *FeedDiscoverer) Discover(ctx context.Context, pageURL string) ([]models.FeedCandidate, error) {
+	feeds, err := d.discover(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]models.FeedCandidate, 0, len(feeds))
+	for _, feed := range feeds {
+		candidates = append(candidates, feed.FeedCandidate)
+	}
+	return candidates, nil
+}
+
+func (d *FeedDiscoverer) discover(ctx context.Context, pageURL string) ([]discoveredFeed, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	body, finalURL, contentType, err := d.get(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if feed, err := parseDiscoveredFeed(body, contentType); err == nil {
+		return []discoveredFeed{{
+			FeedCandidate: models.FeedCandidate{URL: pageURL, Title: strings.TrimSpace(feed.Title)},
+			body:          body,
+			contentType:   contentType,
+		}}, nil
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("%w: unexpected content type %s", ErrFeedNotFound, mediaType)
+	}
+
+	decoded, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode page: %w", err)
+	}
+	doc, err := html.Parse(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	links := feedLinks(doc, finalURL)
+	if len(links) == 0 {
+		for _, path := range commonFeedPaths {
+			links = append(links, models.FeedCandidate{URL: finalURL.ResolveReference(&url.URL{Path: path}).String()})
+		}
+	}
+
+	var feeds []discoveredFeed
+	var tooLarge error
+	for _, link := range links {
+		if len(feeds) == maxFeedCandidates || ctx.Err() != nil {
+			break
+		}
+		feed, err := d.probe(ctx, link)
+		if errors.Is(err, ErrFeedTooLarge) && tooLarge == nil {
+			tooLarge = err
+		}
+		if err != nil {
+			continue
+		}
+		feeds = append(feeds, feed)
+	}
+	if len(feeds) == 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Найденная, но слишком большая лента - не то же самое, что ее отсутствие
+		if tooLarge != nil {
+			return nil, tooLarge
+		}
+		return nil, ErrFeedNotFound
+	}
+	return feeds, nil
+}
+
+// probe проверяет, что по ссылке действительно отдается лента, и дополняет
+// кандидата ее заголовком.
+func (d *FeedDiscoverer) probe(ctx context.Context, link models.FeedCandidate) (discoveredFeed, error) {
+	body, _, contentType, err := d.get(ctx, link.URL)
+	if err != nil {
+		return discoveredFeed{}, err
+	}
+	feed, err := parseDiscoveredFeed(body, contentType)
+	if err != nil {
+		return discoveredFeed{}, err
+	}
+	if link.Title == "" {
+		link.Title = strings.TrimSpace(feed.Title)
+	}
+	return discoveredFeed{FeedCandidate: link, body: body, contentType: contentType}, nil
+}
+
+// parseDiscoveredFeed перекодирует ленту в UTF-8 так же, как при обычной
+// загрузке, и разбирает ее.
+func parseDiscoveredFeed(body []byte, contentType string) (*gofeed.Feed, error) {
+	decoded, _, err := DecodeFeedBody(body, contentType, "")
+	if err != nil {
+		return nil, err
+	}
+	return gofeed.NewParser().Parse(bytes.NewReader(decoded))
+}
+
+func (d *FeedDiscoverer) get(ctx context.Context, target string) ([]byte, *url.URL, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, text/html;q=0.9, */*;q=0.5")
+
+	release, err := d.hosts.acquire(ctx, strings.ToLower(req.URL.Hostname()))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer release()
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, "", &FetchError{StatusCode: resp.StatusCode, Err: fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)}
+	}
+	body, err := readLimitedBody(resp, d.maxBody)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return body, resp.Request.URL, resp.Header.Get("Content-Type"), nil
+}
+
+// feedLinks собирает ссылки <link rel="alternate"> на ленты из HTML-страницы.
+func feedLinks(doc *html.Node, base *url.URL) []models.FeedCandidate {
+	var links []models.FeedCandidate
+	seen := make(map[string]bool)
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.DataAtom == atom.Link {
+			var rel, linkType, href, title string
+			for _, attr := range node.Attr {
+				switch attr.Key {
+				case "rel":
+					rel = strings.ToLower(attr.Val)
+				case "type":
+					linkType = strings.ToLower(strings.TrimSpace(attr.Val))
+				case "href":
+					href = strings.TrimSpace(attr.Val)
+				case "title":
+					title = strings.TrimSpace(attr.Val)
+				}
+			}
+			if strings.Contains(rel, "alternate") && feedLinkTypes[linkType] && href != "" {
+				if ref, err := url.Parse(href); err == nil {
+					resolved := base.ResolveReference(ref).String()
+					if !seen[resolved] {
+						seen[resolved] = true
+						links = append(links, models.FeedCandidate{URL: resolved, Title: title})
+					}
+				}
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return links
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func feedXML(title string) string {
+	return fmt.Sprintf(`<?xml version="1.0"?><rss version="2.0"><channel><title>%s</title>
+<item><title>Item</title><link>https://example.com/1</link></item></channel></rss>`, title)
+}
+
+func newDiscoveryServer(t *testing.T, pages map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasPrefix(body, "<?xml") {
+			w.Header().Set("Content-Type", "application/rss+xml")
+		} else {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFeedDiscoverer_FeedURL(t *testing.T) {
+	server := newDiscoveryServer(t, map[string]string{"/rss": feedXML("Direct")})
+
+	candidates, err := NewFeedDiscoverer(loopbackGuard(t), nil).Discover(context.Background(), server.URL+"/rss")
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, server.URL+"/rss", candidates[0].URL)
+	assert.Equal(t, "Direct", candidates[0].Title)
+}
+
+func TestFeedDiscoverer_AlternateLinks(t *testing.T) {
+	server := newDiscoveryServer(t, map[string]string{
+		"/": `<html><head>
+<link rel="alternate" type="application/rss+xml" title="Все статьи" href="/news/rss.xml">
+<link rel="alternate" type="application/atom+xml" href="/atom">
+<link rel="alternate" type="application/rss+xml" href="/broken.xml">
+<link rel="stylesheet" href="/style.css">
+</head><body>Главная</body></html>`,
+		"/news/rss.xml": feedXML("News"),
+		"/atom":         feedXML("Atom feed"),
+	})
+
+	candidates, err := NewFeedDiscoverer(loopbackGuard(t), nil).Discover(context.Background(), server.URL+"/")
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+	assert.Equal(t, server.URL+"/news/rss.xml", candidates[0].URL)
+	assert.Equal(t, "Все статьи", candidates[0].Title)
+	assert.Equal(t, server.URL+"/atom", candidates[1].URL)
+	assert.Equal(t, "Atom feed", candidates[1].Title)
+}
+
+func TestFeedDiscoverer_CommonPaths(t *testing.T) {
+	server := newDiscoveryServer(t, map[string]string{
+		"/blog":     `<html><body>Блог без ссылки на ленту</body></html>`,
+		"/feed.xml": feedXML("Blog"),
+	})
+
+	candidates, err := NewFeedDiscoverer(loopbackGuard(t), nil).Discover(context.Background(), server.URL+"/blog")
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, server.URL+"/feed.xml", candidates[0].URL)
+	assert.Equal(t, "Blog", candidates[0].Title)
+}
+
+func TestFeedDiscoverer_NotFound(t *testing.T) {
+	server := newDiscoveryServer(t, map[string]string{
+		"/": `<html><body>Ничего нет</body></html>`,
+	})
+
+	_, err := NewFeedDiscoverer(loopbackGuard(t), nil).Discover(context.Background(), server.URL+"/")
+	assert.ErrorIs(t, err, ErrFeedNotFound)
+}
+
+func TestFeedDiscoverer_OverallDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			_, _ = w.Write([]byte(`<html><body>Ничего нет</body></html>`))
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	discoverer := NewFeedDiscoverer(loopbackGuard(t), nil)
+	discoverer.timeout = 200 * time.Millisecond
+
+	started := time.Now()
+	_, err := discoverer.Discover(context.Background(), server.URL+"/")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), 2*time.Second)
+}
+
+func TestFeedDiscoverer_DecodesFeedCharset(t *testing.T) {
+	feed := encodeFeed(t, "windows-1251", "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(`<html><head><link rel="alternate" type="application/rss+xml" href="/rss"></head></html>`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml; charset=windows-1251")
+		_, _ = w.Write(feed)
+	}))
+	t.Cleanup(server.Close)
+
+	candidates, err := NewFeedDiscoverer(loopbackGuard(t), nil).Discover(context.Background(), server.URL+"/")
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, cyrillicTitle, candidates[0].Title)
+}
+
+func TestFeedDiscoverer_FeedTooLarge(t *testing.T) {
+	server := newDiscoveryServer(t, map[string]string{
+		"/":    `<html><head><link rel="alternate" type="application/rss+xml" href="/rss"></head></html>`,
+		"/rss": feedXML(strings.Repeat("x", 4096)),
+	})
+
+	discoverer := NewFeedDiscoverer(loopbackGuard(t), nil)
+	discoverer.maxBody = 1024
+
+	_, err := discoverer.Discover(context.Background(), server.URL+"/")
+	assert.ErrorIs(t, err, ErrFeedTooLarge)
+}
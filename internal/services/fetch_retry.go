@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy задает повторные попытки загрузки ленты. Нулевые поля
+// заменяются значениями из DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts - число попыток, включая первую
+	MaxAttempts int
+	// BaseDelay - пауза перед второй попыткой, дальше она удваивается до MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// MaxRetryAfter ограничивает, на сколько Retry-After может отложить источник
+	MaxRetryAfter time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:   3,
+		BaseDelay:     time.Second,
+		MaxDelay:      30 * time.Second,
+		MaxRetryAfter: 6 * time.Hour,
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	defaults := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaults.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaults.MaxDelay
+	}
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+	if p.MaxRetryAfter <= 0 {
+		p.MaxRetryAfter = defaults.MaxRetryAfter
+	}
+	return p
+}
+
+// Backoff возвращает паузу перед попыткой attempt+1: экспоненциальный рост от
+// BaseDelay до MaxDelay со случайным разбросом в верхней половине интервала,
+// чтобы источники одного хоста не повторяли запросы одновременно.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// isPermanentStatus сообщает, что повтор запроса не изменит ответ сервера.
+func isPermanentStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return false
+	}
+	return statusCode >= 400 && statusCode < 500
+}
+
+// statusError описывает неуспешный HTTP-ответ. Retry-After учитывается только
+// для 429 и 503: такой источник откладывается, а не повторяется сразу.
+func (p RetryPolicy) statusError(resp *http.Response, now time.Time) *FetchError {
+	fetchErr := &FetchError{
+		StatusCode: resp.StatusCode,
+		Err:        errors.New("unexpected HTTP status " + strconv.Itoa(resp.StatusCode)),
+		Permanent:  isPermanentStatus(resp.StatusCode),
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+			fetchErr.RetryAfter = min(delay, p.MaxRetryAfter)
+		}
+	}
+	return fetchErr
+}
+
+// parseRetryAfter разбирает Retry-After в секундах или в формате HTTP-даты.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(date.Sub(now), 0), true
+}
+
+// RetryAfterOf возвращает срок, на который сервер попросил отложить источник.
+func RetryAfterOf(err error) time.Duration {
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		return fetchErr.RetryAfter
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 10, 10, 0, 0, 0, time.UTC)
+
+	delay, ok := parseRetryAfter("120", now)
+	require.True(t, ok)
+	assert.Equal(t, 2*time.Minute, delay)
+
+	delay, ok = parseRetryAfter(now.Add(time.Hour).Format(http.TimeFormat), now)
+	require.True(t, ok)
+	assert.Equal(t, time.Hour, delay)
+
+	delay, ok = parseRetryAfter(now.Add(-time.Hour).Format(http.TimeFormat), now)
+	require.True(t, ok)
+	assert.Zero(t, delay)
+
+	_, ok = parseRetryAfter("", now)
+	assert.False(t, ok)
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}.withDefaults()
+
+	for range 50 {
+		first := policy.Backoff(1)
+		assert.GreaterOrEqual(t, first, 500*time.Millisecond)
+		assert.LessOrEqual(t, first, time.Second)
+
+		third := policy.Backoff(3)
+		assert.GreaterOrEqual(t, third, 2*time.Second)
+		assert.LessOrEqual(t, third, 4*time.Second)
+
+		capped := policy.Backoff(10)
+		assert.GreaterOrEqual(t, capped, 5*time.Second)
+		assert.LessOrEqual(t, capped, 10*time.Second)
+	}
+}
+
+func TestRssParser_ParseURL_RetryClassification(t *testing.T) {
+	var notFound, flaky, limited atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		notFound.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		if flaky.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(testFeed))
+	})
+	mux.HandleFunc("/limited", func(w http.ResponseWriter, r *http.Request) {
+		limited.Add(1)
+		w.Header().Set("Retry-After", "7200")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	parser := NewRssParser(1, loopbackGuard(t), FetchLimits{}, RetryPolicy{
+		MaxAttempts:   3,
+		BaseDelay:     time.Millisecond,
+		MaxDelay:      5 * time.Millisecond,
+		MaxRetryAfter: time.Hour,
+	})
+
+	_, err := parser.ParseURL(context.Background(), server.URL+"/missing", FetchOptions{})
+	require.Error(t, err)
+	assert.Equal(t, int32(1), notFound.Load())
+
+	result, err := parser.ParseURL(context.Background(), server.URL+"/flaky", FetchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, result.Items, 1)
+	assert.Equal(t, int32(3), flaky.Load())
+
+	_, err = parser.ParseURL(context.Background(), server.URL+"/limited", FetchOptions{})
+	require.Error(t, err)
+	assert.Equal(t, int32(1), limited.Load())
+	assert.Equal(t, time.Hour, RetryAfterOf(err))
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+)
+
+const (
+	defaultMinFetchInterval = 5 * time.Minute
+	defaultMaxFetchInterval = 24 * time.Hour
+	publishSampleSize       = 20
+)
+
+// FetchIntervalBounds возвращает границы интервала опроса источника.
+func FetchIntervalBounds(source models.Source) (time.Duration, time.Duration) {
+	minInterval := time.Duration(source.MinFetchInterval) * time.Second
+	maxInterval := time.Duration(source.MaxFetchInterval) * time.Second
+	if minInterval <= 0 {
+		minInterval = defaultMinFetchInterval
+	}
+	if maxInterval <= 0 {
+		maxInterval = defaultMaxFetchInterval
+	}
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+	return minInterval, maxInterval
+}
+
+// AdaptFetchInterval подбирает следующий интервал опроса под частоту публикаций.
+// Если по датам новостей удается оценить средний промежуток между публикациями,
+// источник опрашивается вдвое чаще него. Иначе интервал сокращается вдвое при
+// появлении новых новостей и увеличивается в полтора раза, если их не было.
+func AdaptFetchInterval(current, minInterval, maxInterval time.Duration, items []RssItem, newItems int, now time.Time) time.Duration {
+	var next time.Duration
+	switch estimate := estimatePublishInterval(items, now); {
+	case estimate > 0:
+		next = estimate / 2
+	case newItems > 0:
+		next = current / 2
+	default:
+		next = current * 3 / 2
+	}
+
+	if next < minInterval {
+		return minInterval
+	}
+	if next > maxInterval {
+		return maxInterval
+	}
+	return next
+}
+
+// estimatePublishInterval возвращает медианный промежуток между последними
+// публикациями. Если лента давно не обновлялась, возвращается время с момента
+// последней публикации. Промежутки короче секунды не учитываются: так бывает
+// у лент без дат, где время публикации подставляется при разборе.
+func estimatePublishInterval(items []RssItem, now time.Time) time.Duration {
+	dates := make([]time.Time, 0, len(items))
+	for _, item := range items {
+		if item.Date.IsZero() || item.Date.After(now) {
+			continue
+		}
+		dates = append(dates, item.Date)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].After(dates[j])
+	})
+	if len(dates) > publishSampleSize {
+		dates = dates[:publishSampleSize]
+	}
+
+	var gaps []time.Duration
+	for i := 1; i < len(dates); i++ {
+		if gap := dates[i-1].Sub(dates[i]); gap >= time.Second {
+			gaps = append(gaps, gap)
+		}
+	}
+	if len(gaps) < 2 {
+		return 0
+	}
+
+	sort.Slice(gaps, func(i, j int) bool {
+		return gaps[i] < gaps[j]
+	})
+	median := gaps[len(gaps)/2]
+	if idle := now.Sub(dates[0]); idle > median {
+		return idle
+	}
+	return median
+}
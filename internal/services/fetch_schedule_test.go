@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func itemsEvery(now time.Time, gap time.Duration, count int) []RssItem {
+	items := make([]RssItem, 0, count)
+	for i := range count {
+		items = append(items, RssItem{Date: now.Add(-time.Duration(i) * gap)})
+	}
+	return items
+}
+
+func TestAdaptFetchInterval(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	minInterval, maxInterval := 5*time.Minute, 24*time.Hour
+
+	tests := []struct {
+		name     string
+		current  time.Duration
+		items    []RssItem
+		newItems int
+		expected time.Duration
+	}{
+		{
+			name:     "frequent publisher is clamped to min",
+			current:  20 * time.Minute,
+			items:    itemsEvery(now, 2*time.Minute, 10),
+			newItems: 5,
+			expected: minInterval,
+		},
+		{
+			name:     "hourly publisher is polled twice per hour",
+			current:  20 * time.Minute,
+			items:    itemsEvery(now, time.Hour, 10),
+			newItems: 1,
+			expected: 30 * time.Minute,
+		},
+		{
+			name:     "weekly blog is clamped to max",
+			current:  20 * time.Minute,
+			items:    itemsEvery(now, 7*24*time.Hour, 5),
+			expected: maxInterval,
+		},
+		{
+			name:     "idle feed slows down",
+			current:  20 * time.Minute,
+			items:    itemsEvery(now.Add(-10*time.Hour), 10*time.Minute, 10),
+			expected: 5 * time.Hour,
+		},
+		{
+			name:     "no dates and no new items backs off",
+			current:  20 * time.Minute,
+			expected: 30 * time.Minute,
+		},
+		{
+			name:     "no dates with new items speeds up",
+			current:  20 * time.Minute,
+			items:    []RssItem{{Date: now}, {Date: now}, {Date: now}},
+			newItems: 3,
+			expected: 10 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AdaptFetchInterval(tt.current, minInterval, maxInterval, tt.items, tt.newItems, now)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestFetchIntervalBounds(t *testing.T) {
+	minInterval, maxInterval := FetchIntervalBounds(models.Source{MinFetchInterval: 600, MaxFetchInterval: 3600})
+	assert.Equal(t, 10*time.Minute, minInterval)
+	assert.Equal(t, time.Hour, maxInterval)
+
+	minInterval, maxInterval = FetchIntervalBounds(models.Source{})
+	assert.Equal(t, defaultMinFetchInterval, minInterval)
+	assert.Equal(t, defaultMaxFetchInterval, maxInterval)
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"mime"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+const (
+	maxMediaPerItem   = 10
+	maxMediaURLLength = 1000
+)
+
+var mediaKindsByExtension = map[string]string{
+	".jpg":  models.MediaKindImage,
+	".jpeg": models.MediaKindImage,
+	".png":  models.MediaKindImage,
+	".gif":  models.MediaKindImage,
+	".webp": models.MediaKindImage,
+	".mp3":  models.MediaKindAudio,
+	".m4a":  models.MediaKindAudio,
+	".ogg":  models.MediaKindAudio,
+	".oga":  models.MediaKindAudio,
+	".wav":  models.MediaKindAudio,
+	".mp4":  models.MediaKindVideo,
+	".m4v":  models.MediaKindVideo,
+	".webm": models.MediaKindVideo,
+	".mov":  models.MediaKindVideo,
+}
+
+// ExtractMedia собирает вложения элемента ленты: enclosure, media:content
+// (в том числе внутри media:group), media:thumbnail и изображение элемента.
+// Вложения без ссылки или с неизвестным типом пропускаются, повторы по ссылке
+// объединяются.
+func ExtractMedia(item *gofeed.Item) []models.NewsMedia {
+	var base *url.URL
+	if item.Link != "" {
+		base, _ = url.Parse(strings.TrimSpace(item.Link))
+	}
+
+	var media []models.NewsMedia
+	seen := make(map[string]bool)
+	add := func(rawURL, mimeType, medium, size string) {
+		if len(media) == maxMediaPerItem {
+			return
+		}
+		mediaURL, ok := resolveMediaURL(base, rawURL)
+		if !ok || seen[mediaURL] {
+			return
+		}
+		kind := mediaKind(mimeType, medium, mediaURL)
+		if kind == "" {
+			return
+		}
+		seen[mediaURL] = true
+
+		entry := models.NewsMedia{
+			URL:      mediaURL,
+			Kind:     kind,
+			Position: len(media),
+		}
+		if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+			entry.MimeType = &mediaType
+		}
+		if bytes, err := strconv.ParseInt(strings.TrimSpace(size), 10, 64); err == nil && bytes > 0 {
+			entry.Size = &bytes
+		}
+		media = append(media, entry)
+	}
+
+	for _, enclosure := range item.Enclosures {
+		if enclosure != nil {
+			add(enclosure.URL, enclosure.Type, "", enclosure.Length)
+		}
+	}
+	mediaExt := item.Extensions["media"]
+	for _, content := range mediaContents(mediaExt) {
+		add(content.Attrs["url"], content.Attrs["type"], content.Attrs["medium"], content.Attrs["fileSize"])
+	}
+	if item.Image != nil {
+		add(item.Image.URL, "", models.MediaKindImage, "")
+	}
+	for _, thumbnail := range mediaExt["thumbnail"] {
+		add(thumbnail.Attrs["url"], "", models.MediaKindImage, "")
+	}
+	return media
+}
+
+func mediaContents(mediaExt map[string][]ext.Extension) []ext.Extension {
+	contents := append([]ext.Extension(nil), mediaExt["content"]...)
+	for _, group := range mediaExt["group"] {
+		contents = append(contents, group.Children["content"]...)
+	}
+	return contents
+}
+
+func resolveMediaURL(base *url.URL, raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || raw == "" {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	result := u.String()
+	if len(result) > maxMediaURLLength {
+		return "", false
+	}
+	return result, true
+}
+
+// mediaKind определяет вид вложения по MIME-типу, атрибуту medium и, если
+// они не заданы, по расширению файла.
+func mediaKind(mimeType, medium, mediaURL string) string {
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		switch {
+		case strings.HasPrefix(mediaType, "image/"):
+			return models.MediaKindImage
+		case strings.HasPrefix(mediaType, "audio/"):
+			return models.MediaKindAudio
+		case strings.HasPrefix(mediaType, "video/"):
+			return models.MediaKindVideo
+		}
+	}
+	switch medium {
+	case models.MediaKindImage, models.MediaKindAudio, models.MediaKindVideo:
+		return medium
+	}
+	if u, err := url.Parse(mediaURL); err == nil {
+		return mediaKindsByExtension[strings.ToLower(path.Ext(u.Path))]
+	}
+	return ""
+}
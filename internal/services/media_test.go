@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mediaFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/">
+<channel>
+<title>Test</title>
+<item>
+  <title>Podcast episode</title>
+  <link>https://example.com/podcast/1</link>
+  <enclosure url="https://cdn.example.com/episode1.mp3" length="12345678" type="audio/mpeg"/>
+  <media:thumbnail url="/images/episode1.jpg"/>
+</item>
+<item>
+  <title>Photo report</title>
+  <link>https://example.com/news/2</link>
+  <media:group>
+    <media:content url="https://cdn.example.com/photo.webp" medium="image" fileSize="2048"/>
+    <media:content url="https://cdn.example.com/clip" type="video/mp4"/>
+  </media:group>
+  <media:content url="https://cdn.example.com/photo.webp" type="image/webp"/>
+  <media:content url="https://cdn.example.com/file.bin"/>
+  <enclosure url="javascript:alert(1)" type="image/png"/>
+</item>
+</channel>
+</rss>`
+
+func TestExtractMedia(t *testing.T) {
+	feed, err := gofeed.NewParser().ParseString(mediaFeed)
+	require.NoError(t, err)
+	require.Len(t, feed.Items, 2)
+
+	podcast := ExtractMedia(feed.Items[0])
+	require.Len(t, podcast, 2)
+	assert.Equal(t, "https://cdn.example.com/episode1.mp3", podcast[0].URL)
+	assert.Equal(t, models.MediaKindAudio, podcast[0].Kind)
+	require.NotNil(t, podcast[0].MimeType)
+	assert.Equal(t, "audio/mpeg", *podcast[0].MimeType)
+	require.NotNil(t, podcast[0].Size)
+	assert.Equal(t, int64(12345678), *podcast[0].Size)
+	assert.Equal(t, "https://example.com/images/episode1.jpg", podcast[1].URL)
+	assert.Equal(t, models.MediaKindImage, podcast[1].Kind)
+	assert.Equal(t, 1, podcast[1].Position)
+
+	report := ExtractMedia(feed.Items[1])
+	require.Len(t, report, 2)
+	assert.Equal(t, "https://cdn.example.com/photo.webp", report[0].URL)
+	assert.Equal(t, models.MediaKindImage, report[0].Kind)
+	assert.Equal(t, "https://cdn.example.com/clip", report[1].URL)
+	assert.Equal(t, models.MediaKindVideo, report[1].Kind)
+}
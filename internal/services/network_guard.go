@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("address is not allowed")
+
+// Диапазоны, которые не покрываются методами net.IP: CGNAT, служебные и
+// зарезервированные сети, NAT64.
+var reservedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+	"2001:db8::/32",
+)
+
+// NetworkGuard не дает загрузчикам лент обращаться во внутреннюю сеть:
+// адрес проверяется при каждом соединении уже после разрешения имени, поэтому
+// проверка действует и на редиректы, и на подмену DNS-записей. Сети из
+// allowlist разрешены даже если они внутренние.
+type NetworkGuard struct {
+	allowed []*net.IPNet
+}
+
+func NewNetworkGuard(allowedCIDRs []string) (*NetworkGuard, error) {
+	guard := &NetworkGuard{}
+	for _, cidr := range allowedCIDRs {
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed network %q: %w", cidr, err)
+		}
+		guard.allowed = append(guard.allowed, network)
+	}
+	return guard, nil
+}
+
+// guardOrDefault возвращает guard или защиту без allowlist, если guard не задан.
+func guardOrDefault(guard *NetworkGuard) *NetworkGuard {
+	if guard == nil {
+		return &NetworkGuard{}
+	}
+	return guard
+}
+
+// IsAllowed сообщает, можно ли подключаться к адресу.
+func (g *NetworkGuard) IsAllowed(ip net.IP) bool {
+	for _, network := range g.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Client возвращает HTTP-клиент, который соединяется только с разрешенными
+// адресами и следует только редиректам на http и https. connectTimeout
+// ограничивает установку соединения и TLS, timeout - весь запрос вместе с
+// чтением тела. checkRedirect, если задан, вызывается после проверки схемы.
+func (g *NetworkGuard) Client(connectTimeout, timeout time.Duration, checkRedirect func(*http.Request, []*http.Request) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+		Control:   g.control,
+	}
+	transport := &http.Transport{
+		// Прокси из окружения не используется: иначе проверялся бы адрес прокси
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   connectTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: redirect to unsupported scheme %q", ErrForbiddenAddress, req.URL.Scheme)
+			}
+			if checkRedirect != nil {
+				return checkRedirect(req, via)
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+}
+
+func (g *NetworkGuard) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !g.IsAllowed(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
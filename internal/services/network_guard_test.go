@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loopbackGuard разрешает обращения к httptest-серверам на localhost.
+func loopbackGuard(t *testing.T) *NetworkGuard {
+	t.Helper()
+	guard, err := NewNetworkGuard([]string{"127.0.0.0/8", "::1/128"})
+	require.NoError(t, err)
+	return guard
+}
+
+func TestNetworkGuardIsAllowed(t *testing.T) {
+	guard := guardOrDefault(nil)
+
+	forbidden := []string{
+		"127.0.0.1",
+		"10.1.2.3",
+		"172.16.0.1",
+		"192.168.1.1",
+		"169.254.169.254",
+		"100.64.0.1",
+		"0.0.0.0",
+		"::1",
+		"::ffff:127.0.0.1",
+		"fe80::1",
+		"fd00::1",
+	}
+	for _, addr := range forbidden {
+		assert.False(t, guard.IsAllowed(net.ParseIP(addr)), addr)
+	}
+	assert.True(t, guard.IsAllowed(net.ParseIP("8.8.8.8")))
+	assert.True(t, guard.IsAllowed(net.ParseIP("2a00:1450:4010::8a")))
+}
+
+func TestNewNetworkGuardAllowlist(t *testing.T) {
+	guard, err := NewNetworkGuard([]string{"10.0.0.5", "192.168.0.0/16"})
+	require.NoError(t, err)
+	assert.True(t, guard.IsAllowed(net.ParseIP("10.0.0.5")))
+	assert.False(t, guard.IsAllowed(net.ParseIP("10.0.0.6")))
+	assert.True(t, guard.IsAllowed(net.ParseIP("192.168.10.1")))
+
+	_, err = NewNetworkGuard([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+}
+
+func TestNetworkGuardBlocksInternalFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://10.0.0.1/feed", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(testFeed))
+	}))
+	defer server.Close()
+
+	_, err := NewRssParser(1, nil, FetchLimits{}, RetryPolicy{}).ValidateFeed(context.Background(), server.URL+"/feed")
+	assert.ErrorIs(t, err, ErrInvalidFeed)
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+
+	_, err = NewArticleExtractor(nil).Extract(context.Background(), server.URL+"/feed")
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+
+	_, err = NewRssParser(1, loopbackGuard(t), FetchLimits{}, RetryPolicy{}).ValidateFeed(context.Background(), server.URL+"/redirect")
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+}
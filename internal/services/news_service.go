@@ -2,12 +2,15 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
 	"github.com/SANEKNAYMCHIK/newsBot/internal/repositories"
 )
 
+var ErrNewsNotFound = errors.New("news not found")
+
 type NewsService struct {
 	newsRepo         repositories.NewsRepository
 	sourceRepo       repositories.SourceRepository
@@ -26,7 +29,7 @@ func NewNewsService(
 	}
 }
 
-func (n *NewsService) GetNews(ctx context.Context, userID int64, page, pageSize int) (*models.PaginatedResponse[models.NewsResponse], error) {
+func (n *NewsService) GetNews(ctx context.Context, userID int64, filter models.NewsFilter, page, pageSize int) (*models.PaginatedResponse[models.NewsResponse], error) {
 	if page <= 0 {
 		page = 1
 	}
@@ -34,7 +37,9 @@ func (n *NewsService) GetNews(ctx context.Context, userID int64, page, pageSize
 		pageSize = 20
 	}
 
-	news, total, err := n.newsRepo.GetNewsForUser(ctx, userID, page, pageSize)
+	filter.Tag = TagSlug(filter.Tag)
+	filter.Author = TagSlug(filter.Author)
+	news, total, err := n.newsRepo.GetNewsForUser(ctx, userID, filter, page, pageSize)
 	if err != nil {
 		return nil, err
 	}
@@ -54,6 +59,16 @@ func (n *NewsService) GetNews(ctx context.Context, userID int64, page, pageSize
 			SourceID:    item.SourceID,
 			SourceName:  source.Name,
 			CategoryID:  source.CategoryID,
+
+			ContentText:    item.ContentText,
+			WordCount:      item.WordCount,
+			ReadingMinutes: item.ReadingMinutes,
+			Sources:        item.Sources,
+			Media:          item.Media,
+			Tags:           item.Tags,
+			FetchedAt:      item.FetchedAt,
+			Updated:        item.RevisionCount > 0,
+			UpdatedAt:      item.UpdatedAt,
 		})
 	}
 
@@ -97,6 +112,18 @@ func (s *NewsService) GetNewsByID(ctx context.Context, newsID int) (*models.News
 		SourceID:    news.SourceID,
 		SourceName:  source.Name,
 		CategoryID:  source.CategoryID,
+
+		ContentText:    news.ContentText,
+		WordCount:      news.WordCount,
+		ReadingMinutes: news.ReadingMinutes,
+
+		ArticleHTML: news.ArticleHTML,
+		ArticleText: news.ArticleText,
+		Media:       news.Media,
+		Tags:        news.Tags,
+		FetchedAt:   news.FetchedAt,
+		Updated:     news.RevisionCount > 0,
+		UpdatedAt:   news.UpdatedAt,
 	}, nil
 }
 
@@ -136,6 +163,99 @@ func (s *NewsService) GetNewsBySource(
 			PublishedAt: item.PublishedAt,
 			SourceID:    item.SourceID,
 			SourceName:  source.Name,
+
+			ContentText:    item.ContentText,
+			WordCount:      item.WordCount,
+			ReadingMinutes: item.ReadingMinutes,
+			Media:          item.Media,
+			Tags:           item.Tags,
+			FetchedAt:      item.FetchedAt,
+			Updated:        item.RevisionCount > 0,
+			UpdatedAt:      item.UpdatedAt,
+		})
+	}
+
+	totalPages := 0
+	if total > 0 {
+		totalPages = int(total) / pageSize
+		if int(total)%pageSize > 0 {
+			totalPages++
+		}
+	}
+
+	return &models.PaginatedResponse[models.NewsResponse]{
+		Data:       data,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// GetTags возвращает самые частые рубрики и авторов для фильтрации ленты.
+func (s *NewsService) GetTags(ctx context.Context, kind string, limit int) ([]models.Tag, error) {
+	if kind != "" && kind != models.TagKindCategory && kind != models.TagKindAuthor {
+		return nil, fmt.Errorf("unknown tag kind %q", kind)
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return s.newsRepo.GetTags(ctx, kind, limit)
+}
+
+// AddBookmark сохраняет новость в закладки пользователя.
+func (s *NewsService) AddBookmark(ctx context.Context, userID, newsID int64) error {
+	news, err := s.newsRepo.GetByID(ctx, int(newsID))
+	if err != nil {
+		return err
+	}
+	if news == nil {
+		return ErrNewsNotFound
+	}
+	return s.newsRepo.AddBookmark(ctx, userID, newsID)
+}
+
+func (s *NewsService) RemoveBookmark(ctx context.Context, userID, newsID int64) error {
+	return s.newsRepo.RemoveBookmark(ctx, userID, newsID)
+}
+
+func (s *NewsService) GetBookmarks(ctx context.Context, userID int64, page, pageSize int) (*models.PaginatedResponse[models.NewsResponse], error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	news, total, err := s.newsRepo.GetBookmarks(ctx, userID, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []models.NewsResponse
+	for _, item := range news {
+		source, err := s.sourceRepo.GetByID(ctx, int(item.SourceID))
+		if err != nil || source == nil {
+			continue
+		}
+		data = append(data, models.NewsResponse{
+			ID:          item.ID,
+			Title:       item.Title,
+			Content:     item.Content,
+			URL:         item.URL,
+			PublishedAt: item.PublishedAt,
+			SourceID:    item.SourceID,
+			SourceName:  source.Name,
+			CategoryID:  source.CategoryID,
+
+			ContentText:    item.ContentText,
+			WordCount:      item.WordCount,
+			ReadingMinutes: item.ReadingMinutes,
+			Media:          item.Media,
+			Tags:           item.Tags,
+			FetchedAt:      item.FetchedAt,
+			Updated:        item.RevisionCount > 0,
+			UpdatedAt:      item.UpdatedAt,
 		})
 	}
 
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/repositories"
+)
+
+// PartitionService заранее создает месячные секции news_items, чтобы новые
+// новости не попадали в секцию по умолчанию.
+type PartitionService struct {
+	newsRepo repositories.NewsRepository
+
+	monthsAhead int
+	interval    time.Duration
+}
+
+func NewPartitionService(newsRepo repositories.NewsRepository, monthsAhead int, interval time.Duration) *PartitionService {
+	if monthsAhead <= 0 {
+		monthsAhead = 3
+	}
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return &PartitionService{
+		newsRepo:    newsRepo,
+		monthsAhead: monthsAhead,
+		interval:    interval,
+	}
+}
+
+func (s *PartitionService) Start(ctx context.Context) {
+	log.Printf("Starting PartitionService with interval %v", s.interval)
+	s.EnsurePartitions(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("PartitionService stopping...")
+			return
+		case <-ticker.C:
+			s.EnsurePartitions(ctx)
+		}
+	}
+}
+
+// EnsurePartitions создает секции с текущего месяца на monthsAhead месяцев вперед.
+func (s *PartitionService) EnsurePartitions(ctx context.Context) {
+	now := time.Now().UTC()
+	created, err := s.newsRepo.CreatePartitions(ctx, now, now.AddDate(0, s.monthsAhead, 0))
+	if err != nil {
+		log.Printf("Failed to create news partitions: %v", err)
+		return
+	}
+	if created > 0 {
+		log.Printf("Created %d news partitions", created)
+	}
+}
@@ -2,98 +2,83 @@ package services
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log"
-	"sync"
 	"time"
 
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
 	"github.com/SANEKNAYMCHIK/newsBot/internal/repositories"
 	"github.com/google/uuid"
 )
 
-type RefreshRequest struct {
-	ID        string
-	UserID    int64
-	Timestamp time.Time
-	Status    string
-	Result    int
-}
+var (
+	ErrRefreshTooSoon   = errors.New("пожалуйста, подождите перед следующим обновлением")
+	ErrRefreshQueueFull = errors.New("очередь обновлений переполнена, попробуйте позже")
+)
 
+// RefreshService обрабатывает запросы пользователей на внеочередное
+// обновление новостей. Задачи и ограничение частоты запросов хранятся в
+// Postgres, поэтому задачу, поставленную через API, может выполнить бот, и
+// наоборот, а после перезапуска очередь не теряется.
 type RefreshService struct {
-	rssService       *RssService
-	subscriptionRepo repositories.SubscriptionRepository
-
-	mu              sync.RWMutex
-	userLastRequest map[int64]time.Time
-	minRequestGap   time.Duration
+	rssService  *RssService
+	refreshRepo repositories.RefreshRepository
 
-	requestQueue chan *RefreshRequest
-	maxQueueSize int
-	workers      int
+	minRequestGap time.Duration
+	maxQueueSize  int
+	workers       int
 
-	requests sync.Map
+	pollInterval time.Duration
+	staleAfter   time.Duration
+	maxAttempts  int
 }
 
 func NewRefreshService(
 	rssService *RssService,
-	subscriptionRepo repositories.SubscriptionRepository,
+	refreshRepo repositories.RefreshRepository,
 	workers int,
 	maxQueueSize int,
 	minRequestGap time.Duration,
 ) *RefreshService {
 	return &RefreshService{
-		rssService:       rssService,
-		subscriptionRepo: subscriptionRepo,
-		userLastRequest:  make(map[int64]time.Time),
-		minRequestGap:    minRequestGap,
-		requestQueue:     make(chan *RefreshRequest, maxQueueSize),
-		workers:          workers,
-		maxQueueSize:     maxQueueSize,
+		rssService:    rssService,
+		refreshRepo:   refreshRepo,
+		minRequestGap: minRequestGap,
+		maxQueueSize:  maxQueueSize,
+		workers:       workers,
+		pollInterval:  2 * time.Second,
+		staleAfter:    10 * time.Minute,
+		maxAttempts:   3,
 	}
 }
 
-func (s *RefreshService) RequestRefresh(ctx context.Context, userID int64) (*RefreshRequest, error) {
-	if !s.canRequestRefresh(userID) {
-		return nil, fmt.Errorf("пожалуйста, подождите перед следующим обновлением")
+func (s *RefreshService) RequestRefresh(ctx context.Context, userID int64) (*models.RefreshRequest, error) {
+	queued, err := s.refreshRepo.CountQueued(ctx)
+	if err != nil {
+		return nil, err
 	}
-	if len(s.requestQueue) >= s.maxQueueSize {
-		return nil, fmt.Errorf("очередь обновлений переполнена, попробуйте позже")
+	if queued >= s.maxQueueSize {
+		return nil, ErrRefreshQueueFull
 	}
 
-	req := &RefreshRequest{
-		ID:        uuid.New().String(),
-		UserID:    userID,
-		Timestamp: time.Now(),
-		Status:    "pending",
+	req, err := s.refreshRepo.Enqueue(ctx, uuid.New().String(), userID, s.minRequestGap)
+	if err != nil {
+		return nil, err
 	}
-
-	s.requests.Store(req.ID, req)
-
-	s.mu.Lock()
-	s.userLastRequest[userID] = time.Now()
-	s.mu.Unlock()
-
-	select {
-	case s.requestQueue <- req:
-		req.Status = "queued"
-		s.requests.Store(req.ID, req)
-		log.Printf("Запрос на обновление добавлен в очередь: %s для пользователя %d", req.ID, userID)
-		return req, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
-		return nil, fmt.Errorf("очередь переполнена")
+	if req == nil {
+		return nil, ErrRefreshTooSoon
 	}
+
+	log.Printf("Запрос на обновление добавлен в очередь: %s для пользователя %d", req.ID, userID)
+	return req, nil
 }
 
-func (s *RefreshService) canRequestRefresh(userID int64) bool {
-	s.mu.RLock()
-	lastRequest, exists := s.userLastRequest[userID]
-	s.mu.RUnlock()
-	if !exists {
-		return true
+// GetRequestStatus возвращает задачу по ID или nil, если ее нет.
+func (s *RefreshService) GetRequestStatus(ctx context.Context, requestID string) (*models.RefreshRequest, error) {
+	if _, err := uuid.Parse(requestID); err != nil {
+		return nil, nil
 	}
-	return time.Since(lastRequest) >= s.minRequestGap
+	return s.refreshRepo.GetByID(ctx, requestID)
 }
 
 func (s *RefreshService) Start(ctx context.Context) {
@@ -106,37 +91,41 @@ func (s *RefreshService) Start(ctx context.Context) {
 
 func (s *RefreshService) processQueue(ctx context.Context) {
 	for {
+		req, err := s.refreshRepo.Claim(ctx, s.staleAfter, s.maxAttempts)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to claim refresh request: %v", err)
+		}
+		if req != nil {
+			s.processRequest(ctx, req)
+			continue
+		}
+
 		select {
 		case <-ctx.Done():
 			return
-		case req := <-s.requestQueue:
-			s.processRequest(ctx, req)
+		case <-time.After(s.pollInterval):
 		}
 	}
 }
 
-func (s *RefreshService) processRequest(ctx context.Context, req *RefreshRequest) {
-	req.Status = "processing"
-	s.requests.Store(req.ID, req)
-
+func (s *RefreshService) processRequest(ctx context.Context, req *models.RefreshRequest) {
 	saved, err := s.rssService.FetchForUser(ctx, req.UserID)
+	if ctx.Err() != nil {
+		// Задачу заберет другой процесс, когда она будет считаться зависшей
+		return
+	}
 	if err != nil {
-		req.Status = "failed"
 		log.Printf("Failed to refresh news for user %d: %v", req.UserID, err)
-	} else {
-		req.Status = "completed"
-		req.Result = saved
-		log.Printf("Completed refresh for user %d: saved %d items", req.UserID, saved)
+		if err := s.refreshRepo.Fail(ctx, req.ID, err.Error()); err != nil {
+			log.Printf("Failed to record refresh request %s: %v", req.ID, err)
+		}
+		return
 	}
 
-	s.requests.Store(req.ID, req)
-}
-
-func (s *RefreshService) GetRequestStatus(requestID string) (*RefreshRequest, bool) {
-	if val, ok := s.requests.Load(requestID); ok {
-		return val.(*RefreshRequest), true
+	log.Printf("Completed refresh for user %d: saved %d items", req.UserID, saved)
+	if err := s.refreshRepo.Complete(ctx, req.ID, saved); err != nil {
+		log.Printf("Failed to record refresh request %s: %v", req.ID, err)
 	}
-	return nil, false
 }
 
 func (s *RefreshService) cleanupOldRequests(ctx context.Context) {
@@ -148,21 +137,12 @@ func (s *RefreshService) cleanupOldRequests(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			s.requests.Range(func(key, value interface{}) bool {
-				req := value.(*RefreshRequest)
-				if time.Since(req.Timestamp) > 24*time.Hour {
-					s.requests.Delete(key)
-				}
-				return true
-			})
-
-			s.mu.Lock()
-			for userID, lastRequest := range s.userLastRequest {
-				if time.Since(lastRequest) > 24*time.Hour {
-					delete(s.userLastRequest, userID)
-				}
+			if _, err := s.refreshRepo.FailStale(ctx, s.staleAfter, s.maxAttempts); err != nil {
+				log.Printf("Failed to expire stale refresh requests: %v", err)
+			}
+			if _, err := s.refreshRepo.DeleteFinished(ctx, 24*time.Hour); err != nil {
+				log.Printf("Failed to delete old refresh requests: %v", err)
 			}
-			s.mu.Unlock()
 		}
 	}
 }
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRefreshRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshRepository) CountQueued(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRefreshRepository) Enqueue(ctx context.Context, id string, userID int64, minGap time.Duration) (*models.RefreshRequest, error) {
+	args := m.Called(ctx, id, userID, minGap)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshRequest), args.Error(1)
+}
+
+func (m *MockRefreshRepository) Claim(ctx context.Context, staleAfter time.Duration, maxAttempts int) (*models.RefreshRequest, error) {
+	args := m.Called(ctx, staleAfter, maxAttempts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshRequest), args.Error(1)
+}
+
+func (m *MockRefreshRepository) Complete(ctx context.Context, id string, result int) error {
+	args := m.Called(ctx, id, result)
+	return args.Error(0)
+}
+
+func (m *MockRefreshRepository) Fail(ctx context.Context, id string, lastError string) error {
+	args := m.Called(ctx, id, lastError)
+	return args.Error(0)
+}
+
+func (m *MockRefreshRepository) GetByID(ctx context.Context, id string) (*models.RefreshRequest, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshRequest), args.Error(1)
+}
+
+func (m *MockRefreshRepository) FailStale(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int64, error) {
+	args := m.Called(ctx, staleAfter, maxAttempts)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRefreshRepository) DeleteFinished(ctx context.Context, olderThan time.Duration) (int64, error) {
+	args := m.Called(ctx, olderThan)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestRefreshService_RequestRefreshEnqueues(t *testing.T) {
+	repo := new(MockRefreshRepository)
+	service := NewRefreshService(nil, repo, 1, 10, 3*time.Minute)
+
+	job := &models.RefreshRequest{ID: "job", UserID: 7, Status: models.RefreshStatusQueued}
+	repo.On("CountQueued", mock.Anything).Return(2, nil)
+	repo.On("Enqueue", mock.Anything, mock.Anything, int64(7), 3*time.Minute).Return(job, nil)
+
+	req, err := service.RequestRefresh(context.Background(), 7)
+
+	require.NoError(t, err)
+	assert.Equal(t, job, req)
+	repo.AssertExpectations(t)
+}
+
+func TestRefreshService_RequestRefreshTooSoon(t *testing.T) {
+	repo := new(MockRefreshRepository)
+	service := NewRefreshService(nil, repo, 1, 10, 3*time.Minute)
+
+	repo.On("CountQueued", mock.Anything).Return(0, nil)
+	repo.On("Enqueue", mock.Anything, mock.Anything, int64(7), 3*time.Minute).Return(nil, nil)
+
+	_, err := service.RequestRefresh(context.Background(), 7)
+
+	assert.ErrorIs(t, err, ErrRefreshTooSoon)
+}
+
+func TestRefreshService_RequestRefreshQueueFull(t *testing.T) {
+	repo := new(MockRefreshRepository)
+	service := NewRefreshService(nil, repo, 1, 10, 3*time.Minute)
+
+	repo.On("CountQueued", mock.Anything).Return(10, nil)
+
+	_, err := service.RequestRefresh(context.Background(), 7)
+
+	assert.ErrorIs(t, err, ErrRefreshQueueFull)
+	repo.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRefreshService_GetRequestStatusIgnoresInvalidID(t *testing.T) {
+	repo := new(MockRefreshRepository)
+	service := NewRefreshService(nil, repo, 1, 10, 3*time.Minute)
+
+	req, err := service.GetRequestStatus(context.Background(), "not-a-uuid")
+
+	require.NoError(t, err)
+	assert.Nil(t, req)
+	repo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
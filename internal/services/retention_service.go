@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/SANEKNAYMCHIK/newsBot/internal/repositories"
+)
+
+// ErrRetentionRunning возвращается, если очистка уже выполняется.
+var ErrRetentionRunning = errors.New("retention run is already in progress")
+
+// RetentionService удаляет или архивирует новости старше срока хранения.
+// Срок задается глобально и может быть переопределен для источника.
+type RetentionService struct {
+	retentionRepo repositories.RetentionRepository
+
+	defaultDays  int
+	mode         string
+	interval     time.Duration
+	batchSize    int
+	keyGraceDays int
+	pollInterval time.Duration
+
+	running atomic.Bool
+}
+
+func NewRetentionService(
+	retentionRepo repositories.RetentionRepository,
+	defaultDays int,
+	mode string,
+	interval time.Duration,
+	batchSize int,
+	keyGraceDays int,
+) *RetentionService {
+	if mode != models.RetentionModeDelete {
+		mode = models.RetentionModeArchive
+	}
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	return &RetentionService{
+		retentionRepo: retentionRepo,
+		defaultDays:   max(defaultDays, 0),
+		mode:          mode,
+		interval:      interval,
+		batchSize:     batchSize,
+		keyGraceDays:  max(keyGraceDays, 0),
+		pollInterval:  5 * time.Second,
+	}
+}
+
+// Start выполняет очистку по расписанию и запуски, поставленные в очередь
+// через RequestRun. Запускается только в процессе-лидере.
+func (s *RetentionService) Start(ctx context.Context) {
+	log.Printf("Starting RetentionService with interval %v", s.interval)
+	if failed, err := s.retentionRepo.FailInterruptedRuns(ctx); err != nil {
+		log.Printf("Failed to close interrupted retention runs: %v", err)
+	} else if failed > 0 {
+		log.Printf("Closed %d retention runs interrupted by the previous leader", failed)
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	poll := time.NewTicker(s.pollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("RetentionService stopping...")
+			return
+		case <-ticker.C:
+			if _, err := s.RunOnce(ctx, models.RetentionTriggerSchedule); err != nil && !errors.Is(err, ErrRetentionRunning) {
+				log.Printf("Retention run failed: %v", err)
+			}
+		case <-poll.C:
+			if err := s.RunQueued(ctx); err != nil && !errors.Is(err, ErrRetentionRunning) {
+				log.Printf("Queued retention run failed: %v", err)
+			}
+		}
+	}
+}
+
+// RequestRun ставит ручной запуск очистки в очередь и сразу возвращает его.
+// Запуск выполняет процесс-лидер.
+func (s *RetentionService) RequestRun(ctx context.Context) (*models.RetentionRun, error) {
+	return s.retentionRepo.EnqueueRun(ctx, s.mode)
+}
+
+// GetRun возвращает запуск очистки по ID.
+func (s *RetentionService) GetRun(ctx context.Context, id int64) (*models.RetentionRun, error) {
+	return s.retentionRepo.GetRun(ctx, id)
+}
+
+// RunOnce удаляет устаревшие новости порциями по batchSize, пока они не
+// закончатся, и записывает результат в журнал запусков. Одновременно
+// выполняется не больше одной очистки.
+func (s *RetentionService) RunOnce(ctx context.Context, trigger string) (*models.RetentionRun, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return nil, ErrRetentionRunning
+	}
+	defer s.running.Store(false)
+
+	run := &models.RetentionRun{Trigger: trigger, Mode: s.mode}
+	if err := s.retentionRepo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, s.execute(ctx, run)
+}
+
+// RunQueued выполняет запуски из очереди, пока она не опустеет.
+func (s *RetentionService) RunQueued(ctx context.Context) error {
+	if !s.running.CompareAndSwap(false, true) {
+		return ErrRetentionRunning
+	}
+	defer s.running.Store(false)
+
+	for ctx.Err() == nil {
+		run, err := s.retentionRepo.ClaimQueuedRun(ctx)
+		if err != nil || run == nil {
+			return err
+		}
+		run.Mode = s.mode
+		if err := s.execute(ctx, run); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *RetentionService) execute(ctx context.Context, run *models.RetentionRun) error {
+	runErr := s.purge(ctx, run)
+	if runErr != nil {
+		message := runErr.Error()
+		run.Error = &message
+	}
+	if err := s.retentionRepo.FinishRun(context.WithoutCancel(ctx), run); err != nil {
+		log.Printf("Failed to record retention run %d: %v", run.ID, err)
+	}
+	if runErr != nil {
+		return runErr
+	}
+
+	if run.RemovedCount > 0 {
+		log.Printf("Retention removed %d news items (%d archived)", run.RemovedCount, run.ArchivedCount)
+	}
+	return nil
+}
+
+func (s *RetentionService) purge(ctx context.Context, run *models.RetentionRun) error {
+	archive := s.mode == models.RetentionModeArchive
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		removed, archived, err := s.retentionRepo.PurgeExpired(ctx, s.defaultDays, s.batchSize, archive)
+		if err != nil {
+			return err
+		}
+		run.RemovedCount += removed
+		run.ArchivedCount += archived
+		if removed < int64(s.batchSize) {
+			break
+		}
+	}
+
+	// Ключи удаленных новостей держатся дольше самих новостей, пока ленты
+	// могут их отдавать, а затем тоже удаляются
+	if s.keyGraceDays == 0 {
+		return nil
+	}
+	var keysRemoved int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		removed, err := s.retentionRepo.PurgeExpiredKeys(ctx, s.defaultDays, s.keyGraceDays, s.batchSize)
+		if err != nil {
+			return err
+		}
+		keysRemoved += removed
+		if removed < int64(s.batchSize) {
+			break
+		}
+	}
+	if keysRemoved > 0 {
+		log.Printf("Retention removed %d dedup keys of purged news", keysRemoved)
+	}
+	return nil
+}
+
+// GetStatus возвращает текущую политику хранения и последние запуски очистки.
+func (s *RetentionService) GetStatus(ctx context.Context, limit int) (*models.RetentionStatusResponse, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	runs, err := s.retentionRepo.GetRuns(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	// Очистку выполняет лидер, поэтому состояние берется из журнала запусков
+	running := false
+	for _, run := range runs {
+		if run.Status == models.RetentionStatusRunning {
+			running = true
+		}
+	}
+	return &models.RetentionStatusResponse{
+		DefaultDays: s.defaultDays,
+		Mode:        s.mode,
+		Interval:    int(s.interval / time.Hour),
+		Running:     running,
+		Runs:        runs,
+	}, nil
+}
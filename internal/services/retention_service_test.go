@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRetentionRepository struct {
+	mock.Mock
+}
+
+func (m *MockRetentionRepository) PurgeExpired(ctx context.Context, defaultDays, batchSize int, archive bool) (int64, int64, error) {
+	args := m.Called(ctx, defaultDays, batchSize, archive)
+	return args.Get(0).(int64), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRetentionRepository) PurgeExpiredKeys(ctx context.Context, defaultDays, graceDays, batchSize int) (int64, error) {
+	args := m.Called(ctx, defaultDays, graceDays, batchSize)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRetentionRepository) CreateRun(ctx context.Context, run *models.RetentionRun) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *MockRetentionRepository) EnqueueRun(ctx context.Context, mode string) (*models.RetentionRun, error) {
+	args := m.Called(ctx, mode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RetentionRun), args.Error(1)
+}
+
+func (m *MockRetentionRepository) ClaimQueuedRun(ctx context.Context) (*models.RetentionRun, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RetentionRun), args.Error(1)
+}
+
+func (m *MockRetentionRepository) FailInterruptedRuns(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRetentionRepository) GetRun(ctx context.Context, id int64) (*models.RetentionRun, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RetentionRun), args.Error(1)
+}
+
+func (m *MockRetentionRepository) FinishRun(ctx context.Context, run *models.RetentionRun) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *MockRetentionRepository) GetRuns(ctx context.Context, limit int) ([]models.RetentionRun, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RetentionRun), args.Error(1)
+}
+
+func TestRetentionService_RunOncePurgesInBatches(t *testing.T) {
+	repo := new(MockRetentionRepository)
+	service := NewRetentionService(repo, 30, models.RetentionModeArchive, time.Hour, 100, 0)
+
+	repo.On("CreateRun", mock.Anything, mock.Anything).Return(nil)
+	repo.On("PurgeExpired", mock.Anything, 30, 100, true).Return(int64(100), int64(100), nil).Once()
+	repo.On("PurgeExpired", mock.Anything, 30, 100, true).Return(int64(40), int64(40), nil).Once()
+	repo.On("FinishRun", mock.Anything, mock.Anything).Return(nil)
+
+	run, err := service.RunOnce(context.Background(), models.RetentionTriggerManual)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.RetentionTriggerManual, run.Trigger)
+	assert.Equal(t, models.RetentionModeArchive, run.Mode)
+	assert.Equal(t, int64(140), run.RemovedCount)
+	assert.Equal(t, int64(140), run.ArchivedCount)
+	assert.Nil(t, run.Error)
+	repo.AssertExpectations(t)
+}
+
+func TestRetentionService_RunOnceRecordsError(t *testing.T) {
+	repo := new(MockRetentionRepository)
+	service := NewRetentionService(repo, 30, models.RetentionModeDelete, time.Hour, 100, 0)
+
+	repo.On("CreateRun", mock.Anything, mock.Anything).Return(nil)
+	repo.On("PurgeExpired", mock.Anything, 30, 100, false).Return(int64(0), int64(0), errors.New("db down"))
+	repo.On("FinishRun", mock.Anything, mock.MatchedBy(func(run *models.RetentionRun) bool {
+		return run.Error != nil && *run.Error == "db down"
+	})).Return(nil)
+
+	_, err := service.RunOnce(context.Background(), models.RetentionTriggerSchedule)
+
+	assert.Error(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestRetentionService_RunOnceRejectsConcurrentRun(t *testing.T) {
+	repo := new(MockRetentionRepository)
+	service := NewRetentionService(repo, 30, models.RetentionModeArchive, time.Hour, 100, 0)
+
+	service.running.Store(true)
+	_, err := service.RunOnce(context.Background(), models.RetentionTriggerManual)
+
+	assert.ErrorIs(t, err, ErrRetentionRunning)
+	repo.AssertNotCalled(t, "CreateRun", mock.Anything, mock.Anything)
+}
+
+func TestRetentionService_RequestRunEnqueues(t *testing.T) {
+	repo := new(MockRetentionRepository)
+	service := NewRetentionService(repo, 30, models.RetentionModeArchive, time.Hour, 100, 0)
+
+	queued := &models.RetentionRun{ID: 5, Trigger: models.RetentionTriggerManual, Status: models.RetentionStatusQueued}
+	repo.On("EnqueueRun", mock.Anything, models.RetentionModeArchive).Return(queued, nil)
+
+	run, err := service.RequestRun(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), run.ID)
+	repo.AssertNotCalled(t, "PurgeExpired", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRetentionService_RunQueuedExecutesClaimedRuns(t *testing.T) {
+	repo := new(MockRetentionRepository)
+	service := NewRetentionService(repo, 30, models.RetentionModeDelete, time.Hour, 100, 0)
+
+	claimed := &models.RetentionRun{ID: 5, Trigger: models.RetentionTriggerManual, Status: models.RetentionStatusRunning}
+	repo.On("ClaimQueuedRun", mock.Anything).Return(claimed, nil).Once()
+	repo.On("ClaimQueuedRun", mock.Anything).Return(nil, nil).Once()
+	repo.On("PurgeExpired", mock.Anything, 30, 100, false).Return(int64(7), int64(0), nil)
+	repo.On("FinishRun", mock.Anything, mock.MatchedBy(func(run *models.RetentionRun) bool {
+		return run.ID == 5 && run.RemovedCount == 7 && run.Error == nil
+	})).Return(nil)
+
+	err := service.RunQueued(context.Background())
+
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestRetentionService_RunOncePurgesExpiredKeys(t *testing.T) {
+	repo := new(MockRetentionRepository)
+	service := NewRetentionService(repo, 30, models.RetentionModeDelete, time.Hour, 100, 90)
+
+	repo.On("CreateRun", mock.Anything, mock.Anything).Return(nil)
+	repo.On("PurgeExpired", mock.Anything, 30, 100, false).Return(int64(3), int64(0), nil)
+	repo.On("PurgeExpiredKeys", mock.Anything, 30, 90, 100).Return(int64(100), nil).Once()
+	repo.On("PurgeExpiredKeys", mock.Anything, 30, 90, 100).Return(int64(5), nil).Once()
+	repo.On("FinishRun", mock.Anything, mock.Anything).Return(nil)
+
+	run, err := service.RunOnce(context.Background(), models.RetentionTriggerSchedule)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), run.RemovedCount)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+)
+
+// ContentHash - хеш заголовка и текста новости из ленты. Пробелы
+// нормализуются, чтобы переформатирование ленты не считалось правкой.
+func ContentHash(title, text string) string {
+	hash := sha256.Sum256([]byte(strings.Join(strings.Fields(title), " ") + "\x00" + strings.Join(strings.Fields(text), " ")))
+	return hex.EncodeToString(hash[:])
+}
+
+// BatchHash - хеш guid и содержимого всех новостей из одной загрузки ленты.
+// Если он не изменился с прошлой загрузки, правок в ленте нет.
+func BatchHash(news []models.NewsItem) string {
+	hash := sha256.New()
+	for _, item := range news {
+		hash.Write([]byte(item.GUID))
+		hash.Write([]byte{0})
+		hash.Write([]byte(item.ContentHash))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// IsSignificantEdit сообщает, стоит ли снова уведомлять подписчиков о правке:
+// изменился заголовок или текст изменился сильнее, чем отличаются перепечатки
+// одной новости.
+func IsSignificantEdit(edit models.NewsEdit) bool {
+	if !strings.EqualFold(strings.Join(strings.Fields(edit.PreviousTitle), " "), strings.Join(strings.Fields(edit.Title), " ")) {
+		return true
+	}
+	if edit.PreviousFingerprint == nil || edit.Fingerprint == nil {
+		return false
+	}
+	return HammingDistance(uint64(*edit.PreviousFingerprint), uint64(*edit.Fingerprint)) > DuplicateMaxDistance
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentHash(t *testing.T) {
+	hash := ContentHash("Заголовок новости", "Текст новости")
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, ContentHash("  Заголовок   новости ", "Текст\nновости"))
+	assert.NotEqual(t, hash, ContentHash("Заголовок новости (обновлено)", "Текст новости"))
+	assert.NotEqual(t, hash, ContentHash("Заголовок новости", "Текст новости дополнен"))
+}
+
+func TestIsSignificantEdit(t *testing.T) {
+	fingerprint := func(title, text string) *int64 {
+		value, ok := Fingerprint(title, text)
+		require.True(t, ok)
+		signed := int64(value)
+		return &signed
+	}
+	const title = "Центробанк сохранил ключевую ставку"
+	const text = "Совет директоров Банка России на заседании в пятницу сохранил ключевую ставку на прежнем уровне, " +
+		"сообщила пресс-служба регулятора. Решение совпало с ожиданиями большинства аналитиков."
+
+	assert.True(t, IsSignificantEdit(models.NewsEdit{
+		PreviousTitle: title,
+		Title:         "Центробанк снизил ключевую ставку",
+	}))
+	assert.False(t, IsSignificantEdit(models.NewsEdit{
+		PreviousTitle:       title,
+		PreviousFingerprint: fingerprint(title, text),
+		Title:               title,
+		Fingerprint:         fingerprint(title, text+" Фото: пресс-служба."),
+	}))
+	assert.True(t, IsSignificantEdit(models.NewsEdit{
+		PreviousTitle:       title,
+		PreviousFingerprint: fingerprint(title, text),
+		Title:               title,
+		Fingerprint: fingerprint(title, "Обновлено: регулятор неожиданно объявил о внеочередном заседании, "+
+			"на котором рассмотрит меры поддержки рубля и ограничения на вывод капитала из страны."),
+	}))
+}
+
+func TestBatchHash(t *testing.T) {
+	news := []models.NewsItem{
+		{GUID: "1", ContentHash: ContentHash("Заголовок", "Текст")},
+		{GUID: "2", ContentHash: ContentHash("Другой", "Текст")},
+	}
+	same := []models.NewsItem{news[0], news[1]}
+	edited := []models.NewsItem{news[0], {GUID: "2", ContentHash: ContentHash("Другой", "Новый текст")}}
+
+	assert.Equal(t, BatchHash(news), BatchHash(same))
+	assert.NotEqual(t, BatchHash(news), BatchHash(edited))
+}
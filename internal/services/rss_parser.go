@@ -1,112 +1,580 @@
 package services
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
 	"github.com/mmcdole/gofeed"
 )
 
+const userAgent = "newsBot/1.0 (+https://github.com/SANEKNAYMCHIK/newsBot)"
+
 type RssItem struct {
 	Title       string
 	Link        string
 	Date        time.Time
 	Description string
 	GUID        string
+	// FetchedAt - время загрузки, в которой новость была получена
+	FetchedAt time.Time
+
+	// CanonicalLink - ссылка после CanonicalizeURL, по ней новости сравниваются при сохранении.
+	CanonicalLink string
+	Media         []models.NewsMedia
+	Tags          []models.Tag
+}
+
+// FetchOptions содержит валидаторы кеша, сохраненные с прошлой загрузки
+// источника, и заданную для него кодировку.
+type FetchOptions struct {
+	ETag         string
+	LastModified string
+	Encoding     string
+}
+
+const (
+	feedValidationTimeout = 15 * time.Second
+	feedPreviewItems      = 5
+
+	// Даты дальше maxPublishDateSkew в будущем заменяются временем загрузки
+	maxPublishDateSkew = 15 * time.Minute
+)
+
+// Даты раньше minPublishDate считаются ошибкой ленты (нулевой или unix-эпохой).
+var minPublishDate = time.Date(1995, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	ErrInvalidFeed  = errors.New("url is not a valid RSS or Atom feed")
+	ErrFeedTooLarge = errors.New("feed exceeds the maximum response size")
+)
+
+// FetchLimits ограничивает ресурсы, которые может занять загрузка одной ленты.
+// Нулевые поля заменяются значениями из DefaultFetchLimits.
+type FetchLimits struct {
+	// ConnectTimeout - установка соединения и TLS
+	ConnectTimeout time.Duration
+	// ReadTimeout - весь запрос вместе с чтением ответа
+	ReadTimeout  time.Duration
+	MaxBodyBytes int64
+	// MaxItems - сколько новостей из начала ленты обрабатывается за загрузку
+	MaxItems int
+	// MaxPerHost - сколько лент одного хоста загружается одновременно
+	MaxPerHost int
+}
+
+func DefaultFetchLimits() FetchLimits {
+	return FetchLimits{
+		ConnectTimeout: 10 * time.Second,
+		ReadTimeout:    30 * time.Second,
+		MaxBodyBytes:   10 << 20,
+		MaxItems:       200,
+		MaxPerHost:     2,
+	}
+}
+
+func (l FetchLimits) withDefaults() FetchLimits {
+	defaults := DefaultFetchLimits()
+	if l.ConnectTimeout <= 0 {
+		l.ConnectTimeout = defaults.ConnectTimeout
+	}
+	if l.ReadTimeout <= 0 {
+		l.ReadTimeout = defaults.ReadTimeout
+	}
+	if l.MaxBodyBytes <= 0 {
+		l.MaxBodyBytes = defaults.MaxBodyBytes
+	}
+	if l.MaxItems <= 0 {
+		l.MaxItems = defaults.MaxItems
+	}
+	if l.MaxPerHost <= 0 {
+		l.MaxPerHost = defaults.MaxPerHost
+	}
+	return l
+}
+
+type FetchResult struct {
+	Items        []RssItem
+	ETag         string
+	LastModified string
+	NotModified  bool
+	StatusCode   int
+	// RedirectURL заполняется, если источник переехал по постоянному редиректу (301/308).
+	RedirectURL string
+	Latency     time.Duration
+}
+
+// FetchError описывает неудачную загрузку источника. StatusCode равен нулю,
+// если ответ от сервера не был получен. Permanent означает, что повтор не
+// поможет (404, 410, ошибка разбора), RetryAfter - что сервер попросил
+// вернуться позже.
+type FetchError struct {
+	StatusCode int
+	Err        error
+	Permanent  bool
+	RetryAfter time.Duration
+}
+
+func (e *FetchError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// SourceFetch - результат загрузки одного источника в ParseURLsWithPool.
+type SourceFetch struct {
+	Source models.Source
+	Result *FetchResult
+	Err    error
+}
+
+type redirectTraceKey struct{}
+
+type redirectTrace struct {
+	permanent bool
 }
 
 type RssParser struct {
 	parser     *gofeed.Parser
+	client     *http.Client
+	limits     FetchLimits
+	retry      RetryPolicy
+	hosts      *hostLimiter
 	maxWorkers int
-	maxRetries int
 }
 
-func NewRssParser(maxWorkers int) *RssParser {
+func NewRssParser(maxWorkers int, guard *NetworkGuard, limits FetchLimits, retry RetryPolicy) *RssParser {
 	if maxWorkers <= 0 {
 		maxWorkers = 10
 	}
+	limits = limits.withDefaults()
 	return &RssParser{
 		parser:     gofeed.NewParser(),
+		client:     guardOrDefault(guard).Client(limits.ConnectTimeout, limits.ReadTimeout, checkRedirect),
+		limits:     limits,
+		retry:      retry.withDefaults(),
+		hosts:      newHostLimiter(limits.MaxPerHost),
 		maxWorkers: maxWorkers,
-		maxRetries: 3,
 	}
 }
 
-func (p *RssParser) ParseURL(URL string) ([]RssItem, error) {
+// hostLimiter ограничивает число одновременных запросов к одному хосту.
+// Запись хоста удаляется, когда ее освобождает последний запрос, поэтому
+// карта не растет с числом хостов, к которым когда-либо обращались.
+type hostLimiter struct {
+	limit int
+	mu    sync.Mutex
+	slots map[string]*hostSlots
+}
+
+// hostSlots - слоты хоста и число запросов, которые их занимают или ждут.
+type hostSlots struct {
+	ch   chan struct{}
+	refs int
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, slots: make(map[string]*hostSlots)}
+}
+
+// acquire ждет свободный слот хоста и возвращает функцию его освобождения.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	l.mu.Lock()
+	slots, ok := l.slots[host]
+	if !ok {
+		slots = &hostSlots{ch: make(chan struct{}, l.limit)}
+		l.slots[host] = slots
+	}
+	slots.refs++
+	l.mu.Unlock()
+
+	select {
+	case slots.ch <- struct{}{}:
+		return func() {
+			<-slots.ch
+			l.unref(host, slots)
+		}, nil
+	case <-ctx.Done():
+		l.unref(host, slots)
+		return nil, ctx.Err()
+	}
+}
+
+func (l *hostLimiter) unref(host string, slots *hostSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots.refs--
+	if slots.refs == 0 {
+		delete(l.slots, host)
+	}
+}
+
+// limitedBody отдает не больше remaining байт и возвращает ErrFeedTooLarge,
+// если в ответе есть еще данные.
+type limitedBody struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		var probe [1]byte
+		n, err := b.r.Read(probe[:])
+		if n > 0 {
+			return 0, ErrFeedTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// readFeedBody читает тело ответа с учетом MaxBodyBytes.
+func (p *RssParser) readFeedBody(resp *http.Response) ([]byte, error) {
+	return readLimitedBody(resp, p.limits.MaxBodyBytes)
+}
+
+// readLimitedBody читает тело ответа не больше limit байт и возвращает
+// ErrFeedTooLarge, если ответ больше.
+func readLimitedBody(resp *http.Response, limit int64) ([]byte, error) {
+	if resp.ContentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFeedTooLarge, resp.ContentLength)
+	}
+	body, err := io.ReadAll(&limitedBody{r: resp.Body, remaining: limit})
+	if errors.Is(err, ErrFeedTooLarge) {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrFeedTooLarge, limit)
+	}
+	return body, err
+}
+
+// acquireHost занимает слот хоста из адреса запроса.
+func (p *RssParser) acquireHost(ctx context.Context, req *http.Request) (func(), error) {
+	return p.hosts.acquire(ctx, strings.ToLower(req.URL.Hostname()))
+}
+
+// checkRedirect отмечает цепочку редиректов как непостоянную, если в ней
+// встретился хотя бы один ответ, отличный от 301/308.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if trace, ok := req.Context().Value(redirectTraceKey{}).(*redirectTrace); ok {
+		if req.Response == nil ||
+			(req.Response.StatusCode != http.StatusMovedPermanently && req.Response.StatusCode != http.StatusPermanentRedirect) {
+			trace.permanent = false
+		}
+	}
+	return nil
+}
+
+func FetchOptionsFor(source models.Source) FetchOptions {
+	var opts FetchOptions
+	if source.ETag != nil {
+		opts.ETag = *source.ETag
+	}
+	if source.LastModified != nil {
+		opts.LastModified = *source.LastModified
+	}
+	if source.Encoding != nil {
+		opts.Encoding = *source.Encoding
+	}
+	return opts
+}
+
+// ValidateFeed делает одну пробную загрузку ленты с ограничением по времени и
+// размеру и возвращает ее заголовок и первые новости. Ошибки, означающие, что
+// по адресу не лента, оборачивают ErrInvalidFeed.
+func (p *RssParser) ValidateFeed(ctx context.Context, URL string) (*models.FeedPreview, error) {
+	ctx, cancel := context.WithTimeout(ctx, feedValidationTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.8, */*;q=0.5")
+
+	release, err := p.acquireHost(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	resp, err := p.client.Do(req)
+	if errors.Is(err, ErrForbiddenAddress) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFeed, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: HTTP status %d", ErrInvalidFeed, resp.StatusCode)
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil &&
+		(mediaType == "text/html" || mediaType == "application/xhtml+xml" ||
+			strings.HasPrefix(mediaType, "image/") || strings.HasPrefix(mediaType, "audio/") ||
+			strings.HasPrefix(mediaType, "video/")) {
+		return nil, fmt.Errorf("%w: content type %s", ErrInvalidFeed, mediaType)
+	}
+
+	body, err := p.readFeedBody(resp)
+	if errors.Is(err, ErrFeedTooLarge) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFeed, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+	return p.previewFeed(body, resp.Header.Get("Content-Type"))
+}
+
+// previewFeed разбирает уже загруженную ленту и возвращает ее заголовок и
+// первые новости. Ошибки оборачивают ErrInvalidFeed.
+func (p *RssParser) previewFeed(body []byte, contentType string) (*models.FeedPreview, error) {
+	if int64(len(body)) > p.limits.MaxBodyBytes {
+		return nil, fmt.Errorf("%w: %w: %d bytes", ErrInvalidFeed, ErrFeedTooLarge, len(body))
+	}
+	body, _, err := DecodeFeedBody(body, contentType, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+	}
+	feed, err := p.parser.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+	}
+
+	preview := &models.FeedPreview{
+		Title:       strings.TrimSpace(feed.Title),
+		Description: strings.TrimSpace(feed.Description),
+		ItemCount:   len(feed.Items),
+	}
+	for _, item := range feed.Items {
+		if len(preview.Items) == feedPreviewItems {
+			break
+		}
+		rssItem := p.convertToRssItem(item, time.Now())
+		preview.Items = append(preview.Items, models.FeedPreviewItem{
+			Title:       TruncateTitle(rssItem.Title),
+			URL:         rssItem.Link,
+			PublishedAt: rssItem.Date,
+		})
+	}
+	return preview, nil
+}
+
+// ParseURL загружает ленту, повторяя временные ошибки с экспоненциальной
+// паузой. Постоянные ошибки и ответы с Retry-After возвращаются сразу: такой
+// источник откладывается планировщиком и не занимает воркер. Отмена ctx
+// прерывает и текущий запрос, и ожидание перед следующей попыткой.
+func (p *RssParser) ParseURL(ctx context.Context, URL string, opts FetchOptions) (*FetchResult, error) {
 	log.Printf("Started parse source: %s", URL)
-	for i := range p.maxRetries {
-		feed, err := p.parser.ParseURL(URL)
+	var lastErr error
+	for attempt := 1; attempt <= p.retry.MaxAttempts; attempt++ {
+		result, err := p.fetch(ctx, URL, opts)
 		if err == nil {
-			var items []RssItem
-			for _, item := range feed.Items {
-				rssItem := p.convertToRssItem(item, URL)
-				items = append(items, rssItem)
-			}
-			return items, nil
+			return result, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			return nil, &FetchError{Err: ctx.Err()}
+		}
+		var fetchErr *FetchError
+		if errors.As(err, &fetchErr) && (fetchErr.Permanent || fetchErr.RetryAfter > 0) {
+			return nil, fmt.Errorf("failed to parse URL: %s: %w", URL, err)
+		}
+		if attempt == p.retry.MaxAttempts {
+			break
+		}
+		delay := p.retry.Backoff(attempt)
+		log.Printf("Can't parse on attempt %d, retrying in %v: %v", attempt, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, &FetchError{Err: ctx.Err()}
 		}
-		log.Printf("Can't parse on attempt %d", i+1)
-		time.Sleep(time.Second)
 	}
-	return nil, fmt.Errorf("failed to parse URL: %s after %d attempts", URL, p.maxRetries)
+	return nil, fmt.Errorf("failed to parse URL: %s after %d attempts: %w", URL, p.retry.MaxAttempts, lastErr)
 }
 
-func (p *RssParser) convertToRssItem(item *gofeed.Item, URL string) RssItem {
-	publishTime := time.Now()
-	if item.PublishedParsed != nil {
-		publishTime = *item.PublishedParsed
+func (p *RssParser) fetch(ctx context.Context, URL string, opts FetchOptions) (*FetchResult, error) {
+	trace := &redirectTrace{permanent: true}
+	ctx = context.WithValue(ctx, redirectTraceKey{}, trace)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
+	if err != nil {
+		return nil, &FetchError{Err: err, Permanent: true}
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.8, */*;q=0.5")
+	if opts.ETag != "" {
+		req.Header.Set("If-None-Match", opts.ETag)
 	}
-	guid := p.generateGUID(item, URL)
+	if opts.LastModified != "" {
+		req.Header.Set("If-Modified-Since", opts.LastModified)
+	}
+
+	release, err := p.acquireHost(ctx, req)
+	if err != nil {
+		return nil, &FetchError{Err: err}
+	}
+	defer release()
+
+	started := time.Now()
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, &FetchError{Err: err, Permanent: errors.Is(err, ErrForbiddenAddress)}
+	}
+	defer resp.Body.Close()
+
+	result := &FetchResult{
+		StatusCode:   resp.StatusCode,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if finalURL := resp.Request.URL.String(); trace.permanent && finalURL != URL {
+		result.RedirectURL = finalURL
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		if result.ETag == "" {
+			result.ETag = opts.ETag
+		}
+		if result.LastModified == "" {
+			result.LastModified = opts.LastModified
+		}
+		result.Latency = time.Since(started)
+		return result, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, p.retry.statusError(resp, time.Now())
+	}
+
+	body, err := p.readFeedBody(resp)
+	if err != nil {
+		return nil, &FetchError{
+			StatusCode: resp.StatusCode,
+			Err:        err,
+			Permanent:  errors.Is(err, ErrFeedTooLarge),
+		}
+	}
+	body, encoding, err := DecodeFeedBody(body, resp.Header.Get("Content-Type"), opts.Encoding)
+	if err != nil {
+		return nil, &FetchError{StatusCode: resp.StatusCode, Err: err, Permanent: true}
+	}
+	if encoding != "utf-8" {
+		log.Printf("Feed %s decoded from %s", URL, encoding)
+	}
+	feed, err := p.parser.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, &FetchError{
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("failed to parse feed: %w", err),
+			Permanent:  true,
+		}
+	}
+	items := feed.Items
+	if len(items) > p.limits.MaxItems {
+		log.Printf("Feed %s has %d items, processing first %d", URL, len(items), p.limits.MaxItems)
+		items = items[:p.limits.MaxItems]
+	}
+	for _, item := range items {
+		result.Items = append(result.Items, p.convertToRssItem(item, started))
+	}
+	result.Latency = time.Since(started)
+	return result, nil
+}
+
+func (p *RssParser) convertToRssItem(item *gofeed.Item, fetchedAt time.Time) RssItem {
+	guid := p.generateGUID(item)
 	return RssItem{
 		Title:       strings.TrimSpace(item.Title),
 		Link:        strings.TrimSpace(item.Link),
-		Date:        publishTime,
+		Date:        publishDate(item, fetchedAt),
 		Description: strings.TrimSpace(item.Description),
 		GUID:        guid,
+		FetchedAt:   fetchedAt,
+
+		CanonicalLink: canonicalOrRaw(item.Link),
+		Media:         ExtractMedia(item),
+		Tags:          ExtractTags(item),
 	}
 }
 
-func (p *RssParser) generateGUID(item *gofeed.Item, URL string) string {
+// publishDate выбирает дату публикации: published, затем updated, затем время
+// загрузки. Слишком старые даты пропускаются, даты в будущем заменяются
+// временем загрузки.
+func publishDate(item *gofeed.Item, fetchedAt time.Time) time.Time {
+	for _, date := range []*time.Time{item.PublishedParsed, item.UpdatedParsed} {
+		if date == nil || date.Before(minPublishDate) {
+			continue
+		}
+		if date.After(fetchedAt.Add(maxPublishDateSkew)) {
+			return fetchedAt
+		}
+		return *date
+	}
+	return fetchedAt
+}
+
+// generateGUID возвращает guid элемента или хеш его ссылки. Для элементов без
+// ссылки хешируются заголовок и исходная строка даты; время загрузки в хеш не
+// входит, поэтому повторная загрузка дает тот же guid. Адрес ленты тоже не
+// используется: он меняется при переезде источника. У разных лент с одной
+// ссылкой guid совпадет, но guid уникален только в пределах источника
+// (source_id, guid), а одна статья из разных лент и так сводится в одну по
+// канонической ссылке. Новости, сохраненные с прежним guid, получают новый
+// через NewsRepository.AdoptSyntheticGUIDs.
+func (p *RssParser) generateGUID(item *gofeed.Item) string {
 	if item.GUID != "" {
 		return item.GUID
 	}
-	valueForCode := fmt.Sprintf("%s|%s|%v", URL, item.Link, item.Published)
+	valueForCode := canonicalOrRaw(strings.TrimSpace(item.Link))
+	if valueForCode == "" {
+		valueForCode = fmt.Sprintf("%s|%s", strings.TrimSpace(item.Title), item.Published)
+	}
 	hash := sha256.Sum256([]byte(valueForCode))
 	return hex.EncodeToString(hash[:])
 }
 
-func (p *RssParser) ParseURLsWithPool(urls []string) (map[string][]RssItem, error) {
-	if len(urls) == 0 {
-		return make(map[string][]RssItem), nil
+func (p *RssParser) ParseURLsWithPool(ctx context.Context, sources []models.Source) []SourceFetch {
+	if len(sources) == 0 {
+		return nil
 	}
-	tasks := make(chan string, len(urls))
-	res := make(chan struct {
-		url   string
-		items []RssItem
-		err   error
-	}, len(urls))
+	tasks := make(chan models.Source, len(sources))
+	res := make(chan SourceFetch, len(sources))
 
 	var wg sync.WaitGroup
 	for i := 0; i < p.maxWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for url := range tasks {
-				items, err := p.ParseURL(url)
-				res <- struct {
-					url   string
-					items []RssItem
-					err   error
-				}{url, items, err}
+			for source := range tasks {
+				result, err := p.ParseURL(ctx, source.URL, FetchOptionsFor(source))
+				res <- SourceFetch{Source: source, Result: result, Err: err}
 			}
 		}()
 	}
-	for _, url := range urls {
-		tasks <- url
+	for _, source := range sources {
+		tasks <- source
 	}
 	close(tasks)
 	go func() {
@@ -114,18 +582,12 @@ func (p *RssParser) ParseURLsWithPool(urls []string) (map[string][]RssItem, erro
 		close(res)
 	}()
 
-	resItems := make(map[string][]RssItem)
-	var hasErrors bool
+	results := make([]SourceFetch, 0, len(sources))
 	for val := range res {
-		if val.err != nil {
-			log.Printf("Error parsing\nURL:%s\nError:%v", val.url, val.err)
-			hasErrors = true
-			continue
+		if val.Err != nil {
+			log.Printf("Error parsing\nURL:%s\nError:%v", val.Source.URL, val.Err)
 		}
-		resItems[val.url] = val.items
-	}
-	if hasErrors {
-		return resItems, fmt.Errorf("some sources failed to parse")
+		results = append(results, val)
 	}
-	return resItems, nil
+	return results
 }
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+<title>Test feed</title>
+<link>https://example.com/</link>
+<item>
+<title>First item</title>
+<link>https://example.com/first</link>
+<guid>first</guid>
+<description>First description</description>
+<pubDate>Mon, 10 Mar 2025 10:00:00 GMT</pubDate>
+</item>
+</channel>
+</rss>`
+
+func TestRssParser_ParseURL_ConditionalRequest(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 10 Mar 2025 10:00:00 GMT"
+	var requests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(testFeed))
+	}))
+	defer server.Close()
+
+	parser := NewRssParser(1, loopbackGuard(t), FetchLimits{}, RetryPolicy{})
+
+	first, err := parser.ParseURL(context.Background(), server.URL, FetchOptions{})
+	require.NoError(t, err)
+	assert.False(t, first.NotModified)
+	require.Len(t, first.Items, 1)
+	assert.Equal(t, "First item", first.Items[0].Title)
+	assert.Equal(t, etag, first.ETag)
+	assert.Equal(t, lastModified, first.LastModified)
+
+	second, err := parser.ParseURL(context.Background(), server.URL, FetchOptions{ETag: first.ETag, LastModified: first.LastModified})
+	require.NoError(t, err)
+	assert.True(t, second.NotModified)
+	assert.Empty(t, second.Items)
+	assert.Equal(t, etag, second.ETag)
+	assert.Equal(t, lastModified, second.LastModified)
+	assert.Equal(t, 2, requests)
+}
+
+func TestRssParser_ParseURL_Redirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/feed", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/temporary", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusFound)
+	})
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testFeed))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	parser := NewRssParser(1, loopbackGuard(t), FetchLimits{}, RetryPolicy{})
+
+	moved, err := parser.ParseURL(context.Background(), server.URL+"/moved", FetchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/feed", moved.RedirectURL)
+	assert.Len(t, moved.Items, 1)
+
+	temporary, err := parser.ParseURL(context.Background(), server.URL+"/temporary", FetchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, temporary.RedirectURL)
+
+	direct, err := parser.ParseURL(context.Background(), server.URL+"/feed", FetchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, direct.RedirectURL)
+}
+
+func TestRssParser_ParseURL_FailureStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	parser := NewRssParser(1, loopbackGuard(t), FetchLimits{}, RetryPolicy{})
+	parser.retry.MaxAttempts = 1
+
+	_, err := parser.ParseURL(context.Background(), server.URL, FetchOptions{})
+	require.Error(t, err)
+
+	var fetchErr *FetchError
+	require.True(t, errors.As(err, &fetchErr))
+	assert.Equal(t, http.StatusGone, fetchErr.StatusCode)
+}
+
+func TestRssParser_ValidateFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			_, _ = w.Write([]byte(testFeed))
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte("<html><body>Not a feed</body></html>"))
+		case "/broken":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte("<rss><channel><title>Broken"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parser := NewRssParser(1, loopbackGuard(t), FetchLimits{}, RetryPolicy{})
+
+	preview, err := parser.ValidateFeed(context.Background(), server.URL+"/feed")
+	require.NoError(t, err)
+	assert.Equal(t, "Test feed", preview.Title)
+	assert.Equal(t, 1, preview.ItemCount)
+	require.Len(t, preview.Items, 1)
+	assert.Equal(t, "First item", preview.Items[0].Title)
+	assert.Equal(t, "https://example.com/first", preview.Items[0].URL)
+
+	_, err = parser.ValidateFeed(context.Background(), server.URL+"/missing")
+	assert.ErrorIs(t, err, ErrInvalidFeed)
+	assert.Contains(t, err.Error(), "HTTP status 404")
+
+	_, err = parser.ValidateFeed(context.Background(), server.URL+"/page")
+	assert.ErrorIs(t, err, ErrInvalidFeed)
+	assert.Contains(t, err.Error(), "content type text/html")
+
+	_, err = parser.ValidateFeed(context.Background(), server.URL+"/broken")
+	assert.ErrorIs(t, err, ErrInvalidFeed)
+}
+
+func TestRssParser_ParseURL_Limits(t *testing.T) {
+	var items strings.Builder
+	for i := range 5 {
+		fmt.Fprintf(&items, "<item><title>Item %d</title><link>https://example.com/%d</link></item>", i, i)
+	}
+	feed := `<?xml version="1.0"?><rss version="2.0"><channel><title>Big</title>` + items.String() + `</channel></rss>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(feed))
+	}))
+	defer server.Close()
+
+	parser := NewRssParser(1, loopbackGuard(t), FetchLimits{MaxItems: 3}, RetryPolicy{})
+	parser.retry.MaxAttempts = 1
+	result, err := parser.ParseURL(context.Background(), server.URL, FetchOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 3)
+	assert.Equal(t, "Item 0", result.Items[0].Title)
+
+	parser = NewRssParser(1, loopbackGuard(t), FetchLimits{MaxBodyBytes: 100}, RetryPolicy{})
+	parser.retry.MaxAttempts = 1
+	_, err = parser.ParseURL(context.Background(), server.URL, FetchOptions{})
+	assert.ErrorIs(t, err, ErrFeedTooLarge)
+
+	_, err = parser.ValidateFeed(context.Background(), server.URL)
+	assert.ErrorIs(t, err, ErrInvalidFeed)
+	assert.ErrorIs(t, err, ErrFeedTooLarge)
+}
+
+func TestRssParser_ParseURLsWithPool_PerHostLimit(t *testing.T) {
+	var active, maxActive atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := active.Add(1)
+		defer active.Add(-1)
+		for {
+			seen := maxActive.Load()
+			if current <= seen || maxActive.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte(testFeed))
+	}))
+	defer server.Close()
+
+	sources := make([]models.Source, 4)
+	for i := range sources {
+		sources[i] = models.Source{ID: int64(i + 1), URL: fmt.Sprintf("%s/feed/%d", server.URL, i)}
+	}
+
+	parser := NewRssParser(4, loopbackGuard(t), FetchLimits{MaxPerHost: 1}, RetryPolicy{})
+	fetches := parser.ParseURLsWithPool(context.Background(), sources)
+	require.Len(t, fetches, 4)
+	for _, fetch := range fetches {
+		assert.NoError(t, fetch.Err)
+	}
+	assert.Equal(t, int32(1), maxActive.Load())
+}
+
+func TestHostLimiter_ForgetsReleasedHosts(t *testing.T) {
+	limiter := newHostLimiter(1)
+
+	release, err := limiter.acquire(context.Background(), "a.example")
+	require.NoError(t, err)
+
+	// Второй запрос к тому же хосту ждет слот и уходит по отмене контекста
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = limiter.acquire(ctx, "a.example")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	releaseOther, err := limiter.acquire(context.Background(), "b.example")
+	require.NoError(t, err)
+	assert.Len(t, limiter.slots, 2)
+
+	release()
+	releaseOther()
+	assert.Empty(t, limiter.slots)
+}
+
+func TestRssParser_ParseURL_Canceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	parser := NewRssParser(1, loopbackGuard(t), FetchLimits{}, RetryPolicy{})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	started := time.Now()
+	_, err := parser.ParseURL(ctx, server.URL, FetchOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(started), 5*time.Second)
+}
+
+func TestPublishDate(t *testing.T) {
+	fetchedAt := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	published := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	updated := time.Date(2025, 3, 10, 11, 0, 0, 0, time.UTC)
+	future := fetchedAt.Add(48 * time.Hour)
+	epoch := time.Unix(0, 0).UTC()
+
+	assert.Equal(t, published, publishDate(&gofeed.Item{PublishedParsed: &published, UpdatedParsed: &updated}, fetchedAt))
+	assert.Equal(t, updated, publishDate(&gofeed.Item{UpdatedParsed: &updated}, fetchedAt))
+	assert.Equal(t, updated, publishDate(&gofeed.Item{PublishedParsed: &epoch, UpdatedParsed: &updated}, fetchedAt))
+	assert.Equal(t, fetchedAt, publishDate(&gofeed.Item{PublishedParsed: &future}, fetchedAt))
+	assert.Equal(t, fetchedAt, publishDate(&gofeed.Item{}, fetchedAt))
+}
+
+func TestRssParser_GUIDStableAcrossFetches(t *testing.T) {
+	parser := NewRssParser(1, nil, FetchLimits{}, RetryPolicy{})
+	withLink := &gofeed.Item{Title: "Item", Link: "https://example.com/news/1?utm_source=rss"}
+	withoutLink := &gofeed.Item{Title: "Item without link"}
+
+	first := parser.convertToRssItem(withLink, time.Now())
+	second := parser.convertToRssItem(withLink, time.Now().Add(time.Hour))
+	assert.Equal(t, first.GUID, second.GUID)
+	assert.NotEqual(t, first.Date, second.Date)
+
+	first = parser.convertToRssItem(withoutLink, time.Now())
+	second = parser.convertToRssItem(withoutLink, time.Now().Add(time.Hour))
+	assert.Equal(t, first.GUID, second.GUID)
+	assert.NotEqual(t, first.GUID, parser.convertToRssItem(withLink, time.Now()).GUID)
+
+	withLink.GUID = "feed-guid"
+	assert.Equal(t, "feed-guid", parser.convertToRssItem(withLink, time.Now()).GUID)
+}
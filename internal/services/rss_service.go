@@ -2,50 +2,70 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
 	"github.com/SANEKNAYMCHIK/newsBot/internal/repositories"
 )
 
 type RssService struct {
-	sourceRepo repositories.SourceRepository
-	newsRepo   repositories.NewsRepository
-	parser     *RssParser
+	sourceRepo   repositories.SourceRepository
+	newsRepo     repositories.NewsRepository
+	deliveryRepo repositories.DeliveryRepository
+	parser       *RssParser
+	extractor    *ArticleExtractor
+	maxFailures  int
+
+	defaultInterval time.Duration
+	// notifyEdits включает повторную рассылку существенно исправленных новостей
+	notifyEdits bool
+	// revisionHashes - BatchHash последней проверенной на правки загрузки
+	// каждого источника, чтобы не проверять неизменившиеся ленты
+	revisionHashes sync.Map
 }
 
 func NewRssService(
 	sourceRepo repositories.SourceRepository,
 	newsRepo repositories.NewsRepository,
+	deliveryRepo repositories.DeliveryRepository,
 	parser *RssParser,
+	extractor *ArticleExtractor,
+	maxFailures int,
+	defaultInterval time.Duration,
+	notifyEdits bool,
 ) *RssService {
 	return &RssService{
-		sourceRepo: sourceRepo,
-		newsRepo:   newsRepo,
-		parser:     parser,
+		sourceRepo:   sourceRepo,
+		newsRepo:     newsRepo,
+		deliveryRepo: deliveryRepo,
+		parser:       parser,
+		extractor:    extractor,
+		maxFailures:  maxFailures,
+
+		defaultInterval: defaultInterval,
+		notifyEdits:     notifyEdits,
 	}
 }
 
 func (s *RssService) FetchAndSaveNews(ctx context.Context) (int, error) {
-	sources, err := s.sourceRepo.GetActive(ctx)
+	sources, err := s.sourceRepo.GetDue(ctx, time.Now())
 	if err != nil {
-		return 0, fmt.Errorf("failed to get active source: %w", err)
+		return 0, fmt.Errorf("failed to get due sources: %w", err)
 	}
 	if len(sources) == 0 {
-		log.Println("No active sources found")
+		log.Println("No sources due for fetch")
 		return 0, nil
 	}
 	log.Println(len(sources))
-	var urls []string
-	for _, source := range sources {
-		urls = append(urls, source.URL)
-	}
-	parsedResults, err := s.parser.ParseURLsWithPool(urls)
-	if err != nil {
-		log.Printf("Parser completed with errors: %v", err)
-		// Часть источников не спарсилась, но все равно продолжаем работу дальше
+	fetches := s.parser.ParseURLsWithPool(ctx, sources)
+	if ctx.Err() != nil {
+		// Загрузка прервана остановкой: незавершенные запросы не считаются ошибками источников
+		return 0, ctx.Err()
 	}
 	var totalSaved int
 	var mu sync.Mutex
@@ -55,7 +75,7 @@ func (s *RssService) FetchAndSaveNews(ctx context.Context) (int, error) {
 
 	saveTask := make(chan struct {
 		source models.Source
-		items  []RssItem
+		result *FetchResult
 	}, len(sources))
 
 	for i := 0; i < maxSavers; i++ {
@@ -63,7 +83,8 @@ func (s *RssService) FetchAndSaveNews(ctx context.Context) (int, error) {
 		go func() {
 			defer wg.Done()
 			for task := range saveTask {
-				saved := s.saveSourceNews(ctx, task.source, task.items)
+				saved := s.processFetchResult(ctx, task.source, task.result)
+				s.scheduleNextFetch(ctx, task.source, task.result, saved)
 				mu.Lock()
 				totalSaved += saved
 				log.Printf("Successfully saved %d new news items from %s", saved, task.source.Name)
@@ -71,50 +92,327 @@ func (s *RssService) FetchAndSaveNews(ctx context.Context) (int, error) {
 			}
 		}()
 	}
-	for _, source := range sources {
-		if items, ok := parsedResults[source.URL]; ok && len(items) > 0 {
-			saveTask <- struct {
-				source models.Source
-				items  []RssItem
-			}{source, items}
+	for _, fetch := range fetches {
+		s.recordFetch(ctx, &fetch.Source, fetch.Result, fetch.Err)
+		if fetch.Err != nil {
+			// Источник не спарсился, но остальные все равно сохраняем
+			s.scheduleAfterFailure(ctx, fetch.Source, fetch.Err)
+			continue
 		}
+		saveTask <- struct {
+			source models.Source
+			result *FetchResult
+		}{fetch.Source, fetch.Result}
 	}
 	close(saveTask)
 	wg.Wait()
 	return totalSaved, nil
 }
 
-func (s *RssService) saveSourceNews(ctx context.Context, source models.Source, items []RssItem) int {
-	var saved int
+// processFetchResult сохраняет новости источника и запоминает валидаторы кеша.
+// Валидаторы обновляются только если все новости сохранились, иначе при
+// следующем запросе сервер ответит 304 и несохраненные новости потеряются.
+func (s *RssService) processFetchResult(ctx context.Context, source models.Source, result *FetchResult) int {
+	if result.NotModified {
+		log.Printf("Source %s not modified, skipping", source.Name)
+		return 0
+	}
 
+	saved, err := s.saveSourceNews(ctx, source, result.Items)
+	if err != nil {
+		log.Printf("Source %s saved with errors: %v", source.Name, err)
+		return saved
+	}
+
+	if fetchCacheChanged(source, result) {
+		if err := s.sourceRepo.UpdateFetchCache(ctx, source.ID, result.ETag, result.LastModified); err != nil {
+			log.Printf("Failed to update fetch cache for %s: %v", source.Name, err)
+		}
+	}
+	return saved
+}
+
+// recordFetch сохраняет состояние источника после загрузки и отключает его,
+// если загрузка не удается maxFailures раз подряд.
+func (s *RssService) recordFetch(ctx context.Context, source *models.Source, result *FetchResult, fetchErr error) {
+	if fetchErr != nil {
+		var statusCode int
+		var fe *FetchError
+		if errors.As(fetchErr, &fe) {
+			statusCode = fe.StatusCode
+		}
+		deactivated, err := s.sourceRepo.RecordFetchFailure(ctx, source.ID, statusCode, fetchErr.Error(), s.maxFailures)
+		if err != nil {
+			log.Printf("Failed to record fetch failure for %s: %v", source.Name, err)
+			return
+		}
+		if deactivated {
+			log.Printf("Source %s deactivated after %d consecutive failures", source.Name, s.maxFailures)
+		}
+		return
+	}
+
+	if err := s.sourceRepo.RecordFetchSuccess(ctx, source.ID, result.StatusCode, result.Latency); err != nil {
+		log.Printf("Failed to record fetch success for %s: %v", source.Name, err)
+	}
+
+	if result.RedirectURL != "" {
+		canonicalURL, err := CanonicalizeURL(result.RedirectURL)
+		if err != nil {
+			log.Printf("Source %s redirected to invalid url %s: %v", source.Name, result.RedirectURL, err)
+			return
+		}
+		if err := s.sourceRepo.UpdateURL(ctx, source.ID, result.RedirectURL, canonicalURL); err != nil {
+			log.Printf("Failed to move source %s to %s: %v", source.Name, result.RedirectURL, err)
+			return
+		}
+		log.Printf("Source %s permanently moved from %s to %s", source.Name, source.URL, result.RedirectURL)
+		source.URL = result.RedirectURL
+		source.CanonicalURL = canonicalURL
+	}
+}
+
+// scheduleNextFetch назначает время следующего опроса источника. При ошибке
+// загрузки (result == nil) интервал не меняется.
+func (s *RssService) scheduleNextFetch(ctx context.Context, source models.Source, result *FetchResult, saved int) {
+	current := s.currentInterval(source)
+	minInterval, maxInterval := FetchIntervalBounds(source)
+
+	now := time.Now()
+	next := current
+	if result != nil {
+		next = AdaptFetchInterval(current, minInterval, maxInterval, result.Items, saved, now)
+	}
+
+	if err := s.sourceRepo.ScheduleNextFetch(ctx, source.ID, next, now.Add(next)); err != nil {
+		log.Printf("Failed to schedule next fetch for %s: %v", source.Name, err)
+		return
+	}
+	if next != current {
+		log.Printf("Fetch interval for %s changed from %v to %v", source.Name, current, next)
+	}
+}
+
+// scheduleAfterFailure откладывает источник после неудачной загрузки: не
+// раньше срока из Retry-After, если сервер его прислал.
+func (s *RssService) scheduleAfterFailure(ctx context.Context, source models.Source, fetchErr error) {
+	retryAfter := RetryAfterOf(fetchErr)
+	current := s.currentInterval(source)
+	if retryAfter <= current {
+		s.scheduleNextFetch(ctx, source, nil, 0)
+		return
+	}
+	if err := s.sourceRepo.ScheduleNextFetch(ctx, source.ID, current, time.Now().Add(retryAfter)); err != nil {
+		log.Printf("Failed to schedule next fetch for %s: %v", source.Name, err)
+		return
+	}
+	log.Printf("Source %s asked to retry after %v", source.Name, retryAfter)
+}
+
+func (s *RssService) currentInterval(source models.Source) time.Duration {
+	if source.FetchInterval != nil && *source.FetchInterval > 0 {
+		return time.Duration(*source.FetchInterval) * time.Second
+	}
+	return s.defaultInterval
+}
+
+func fetchCacheChanged(source models.Source, result *FetchResult) bool {
+	opts := FetchOptionsFor(source)
+	return opts.ETag != result.ETag || opts.LastModified != result.LastModified
+}
+
+func (s *RssService) saveSourceNews(ctx context.Context, source models.Source, items []RssItem) (int, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	news := make([]models.NewsItem, 0, len(items))
+	oversized := 0
 	for _, item := range items {
-		content := item.Description
-		newsItem := &models.NewsItem{
-			Title:       item.Title,
-			Content:     &content,
+		if field, ok := oversizedNewsKey(item); ok {
+			log.Printf("Source %d (%s): skipping item with %s longer than %d characters: %.200s",
+				source.ID, source.Name, field, maxNewsKeyLength, item.Link)
+			oversized++
+			continue
+		}
+		processed := ProcessContent(item.Description)
+		newsItem := models.NewsItem{
+			Title:       TruncateTitle(item.Title),
+			Content:     &processed.HTML,
 			URL:         item.Link,
 			PublishedAt: item.Date,
 			SourceID:    source.ID,
 			GUID:        item.GUID,
+
+			CanonicalURL:   item.CanonicalLink,
+			ContentText:    &processed.Text,
+			WordCount:      processed.WordCount,
+			ReadingMinutes: processed.ReadingMinutes,
+			Media:          item.Media,
+			Tags:           item.Tags,
+			FetchedAt:      item.FetchedAt,
+			ContentHash:    ContentHash(item.Title, processed.Text),
 		}
+		if fingerprint, ok := Fingerprint(item.Title, processed.Text); ok {
+			value := int64(fingerprint)
+			newsItem.Fingerprint = &value
+		}
+		news = append(news, newsItem)
+	}
 
-		exists, err := s.newsRepo.ExistsByGUID(ctx, int(source.ID), item.GUID)
-		if err != nil {
-			log.Printf("Error checking existence for GUID %s: %v", item.GUID, err)
-			continue
+	// Новости без guid, сохраненные со старым синтетическим guid, получают
+	// новый, иначе они не находятся при дедупликации и отслеживании правок
+	if adopted, err := s.newsRepo.AdoptSyntheticGUIDs(ctx, news); err != nil {
+		log.Printf("Failed to update synthetic guids for %s: %v", source.Name, err)
+	} else if adopted > 0 {
+		log.Printf("Source %s: updated %d synthetic guids", source.Name, adopted)
+	}
+
+	savedIDs, skipped, err := s.newsRepo.CreateBatch(ctx, news)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save %d items: %w", len(items), err)
+	}
+	log.Printf("Source %s: inserted %d, skipped %d existing items, %d items with too long keys",
+		source.Name, len(savedIDs), skipped, oversized)
+
+	// Статьи, уже сохраненные из другой ленты, получают этот источник
+	linkedIDs, err := s.newsRepo.LinkSources(ctx, news)
+	if err != nil {
+		log.Printf("Failed to link existing news to %s: %v", source.Name, err)
+	} else if len(linkedIDs) > 0 {
+		log.Printf("Source %s: linked %d items already saved from other feeds", source.Name, len(linkedIDs))
+	}
+
+	duplicates, err := s.newsRepo.MarkDuplicates(ctx, savedIDs, DuplicateMaxDistance, DuplicateWindow)
+	if err != nil {
+		log.Printf("Failed to detect duplicates for %s: %v", source.Name, err)
+	} else if duplicates > 0 {
+		log.Printf("Source %s: %d new items are copies of news from other sources", source.Name, duplicates)
+	}
+
+	if err := s.newsRepo.CreateMedia(ctx, news); err != nil {
+		log.Printf("Failed to save media for %s: %v", source.Name, err)
+	}
+	if err := s.newsRepo.CreateTags(ctx, news); err != nil {
+		log.Printf("Failed to save tags for %s: %v", source.Name, err)
+	}
+	if source.FetchFullText {
+		s.fetchArticles(ctx, source, news)
+	}
+
+	s.enqueueDeliveries(ctx, source, append(savedIDs, linkedIDs...))
+	s.trackRevisions(ctx, source, news)
+	return len(savedIDs), nil
+}
+
+// maxNewsKeyLength — предельная длина ссылки и guid в news_item_keys.
+const maxNewsKeyLength = 500
+
+// oversizedNewsKey возвращает имя поля новости, которое не помещается в ключи
+// дедупликации.
+func oversizedNewsKey(item RssItem) (string, bool) {
+	switch {
+	case utf8.RuneCountInString(item.Link) > maxNewsKeyLength:
+		return "url", true
+	case utf8.RuneCountInString(item.CanonicalLink) > maxNewsKeyLength:
+		return "canonical url", true
+	case utf8.RuneCountInString(item.GUID) > maxNewsKeyLength:
+		return "guid", true
+	}
+	return "", false
+}
+
+// trackRevisions обновляет уже сохраненные новости, которые источник исправил
+// после публикации, и при notifyEdits снова рассылает существенные правки.
+func (s *RssService) trackRevisions(ctx context.Context, source models.Source, news []models.NewsItem) {
+	existing := make([]models.NewsItem, 0, len(news))
+	for _, item := range news {
+		if item.ID == 0 {
+			existing = append(existing, item)
 		}
+	}
+	if len(existing) == 0 {
+		return
+	}
+	batchHash := BatchHash(existing)
+	if previous, ok := s.revisionHashes.Load(source.ID); ok && previous == batchHash {
+		return
+	}
+
+	edits, err := s.newsRepo.UpdateRevisions(ctx, existing)
+	if err != nil {
+		log.Printf("Failed to track revisions for %s: %v", source.Name, err)
+		return
+	}
+	s.revisionHashes.Store(source.ID, batchHash)
+	if len(edits) == 0 {
+		return
+	}
+	log.Printf("Source %s: %d items were edited", source.Name, len(edits))
+
+	if !s.notifyEdits || s.deliveryRepo == nil {
+		return
+	}
+	var significant []int64
+	for _, edit := range edits {
+		if IsSignificantEdit(edit) {
+			significant = append(significant, edit.NewsID)
+		}
+	}
+	if len(significant) == 0 {
+		return
+	}
+	requeued, err := s.deliveryRepo.RequeueUpdated(ctx, significant)
+	if err != nil {
+		log.Printf("Failed to requeue edited news for %s: %v", source.Name, err)
+		return
+	}
+	log.Printf("Requeued %d deliveries for %d significantly edited items from %s", requeued, len(significant), source.Name)
+}
 
-		if exists {
+// fetchArticles загружает полный текст только что сохраненных новостей
+// источника. Ошибки не мешают сохранению: у новости остается текст из ленты.
+func (s *RssService) fetchArticles(ctx context.Context, source models.Source, news []models.NewsItem) {
+	if s.extractor == nil {
+		return
+	}
+	var fetched, attempted int
+	for _, item := range news {
+		if item.ID == 0 || item.URL == "" {
 			continue
 		}
+		if attempted == maxArticlesPerFetch {
+			log.Printf("Source %s: article limit of %d reached, remaining items keep feed content", source.Name, maxArticlesPerFetch)
+			break
+		}
+		attempted++
 
-		if err := s.newsRepo.Create(ctx, newsItem); err != nil {
-			log.Printf("Failed to save news '%s': %v", item.Title, err)
+		article, err := s.extractor.Extract(ctx, item.URL)
+		if err != nil {
+			log.Printf("Failed to extract article %s from %s: %v", item.URL, source.Name, err)
 			continue
 		}
-		saved++
+		if err := s.newsRepo.UpdateArticle(ctx, item.ID, article.HTML, article.Text, article.WordCount, article.ReadingMinutes); err != nil {
+			log.Printf("Failed to save article %d from %s: %v", item.ID, source.Name, err)
+			continue
+		}
+		fetched++
+	}
+	if attempted > 0 {
+		log.Printf("Source %s: extracted full text for %d of %d items", source.Name, fetched, attempted)
 	}
-	return saved
+}
+
+func (s *RssService) enqueueDeliveries(ctx context.Context, source models.Source, newsIDs []int64) {
+	if s.deliveryRepo == nil || len(newsIDs) == 0 {
+		return
+	}
+	queued, err := s.deliveryRepo.EnqueueForNews(ctx, newsIDs)
+	if err != nil {
+		log.Printf("Failed to enqueue deliveries for %s: %v", source.Name, err)
+		return
+	}
+	log.Printf("Queued %d deliveries for %d new items from %s", queued, len(newsIDs), source.Name)
 }
 
 func (s *RssService) FetchForUser(ctx context.Context, userID int64) (int, error) {
@@ -129,14 +427,20 @@ func (s *RssService) FetchForUser(ctx context.Context, userID int64) (int, error
 
 	var saved int
 	for _, source := range sources {
-		items, err := s.parser.ParseURL(source.URL)
+		result, err := s.parser.ParseURL(ctx, source.URL, FetchOptionsFor(source))
+		if ctx.Err() != nil {
+			return saved, ctx.Err()
+		}
+		s.recordFetch(ctx, &source, result, err)
 		if err != nil {
 			log.Printf("Failed to parse source %s for user %d: %v", source.Name, userID, err)
+			s.scheduleAfterFailure(ctx, source, err)
 			continue
 		}
 
-		count := s.saveSourceNews(ctx, source, items)
-		saved += count
+		sourceSaved := s.processFetchResult(ctx, source, result)
+		s.scheduleNextFetch(ctx, source, result, sourceSaved)
+		saved += sourceSaved
 	}
 
 	return saved, nil
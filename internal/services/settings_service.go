@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/SANEKNAYMCHIK/newsBot/internal/repositories"
+)
+
+type SettingsService struct {
+	settingsRepo repositories.SettingsRepository
+}
+
+func NewSettingsService(settingsRepo repositories.SettingsRepository) *SettingsService {
+	return &SettingsService{settingsRepo: settingsRepo}
+}
+
+func DefaultUserSettings(userID int64) *models.UserSettings {
+	return &models.UserSettings{
+		UserID:        userID,
+		DeliveryMode:  models.DeliveryModeInstant,
+		DigestTime:    "09:00",
+		DigestWeekday: 1,
+		Timezone:      "UTC",
+		GroupBy:       models.DigestGroupBySource,
+	}
+}
+
+func (s *SettingsService) GetSettings(ctx context.Context, userID int64) (*models.UserSettings, error) {
+	settings, err := s.settingsRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return DefaultUserSettings(userID), nil
+	}
+	return settings, nil
+}
+
+func (s *SettingsService) UpdateSettings(ctx context.Context, userID int64, req *models.UpdateSettingsRequest) (*models.UserSettings, error) {
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.DeliveryMode != nil {
+		settings.DeliveryMode = *req.DeliveryMode
+	}
+	if req.DigestTime != nil {
+		settings.DigestTime = *req.DigestTime
+	}
+	if req.DigestWeekday != nil {
+		settings.DigestWeekday = *req.DigestWeekday
+	}
+	if req.Timezone != nil {
+		settings.Timezone = *req.Timezone
+	}
+	if req.GroupBy != nil {
+		settings.GroupBy = *req.GroupBy
+	}
+
+	if err := ValidateUserSettings(settings); err != nil {
+		return nil, err
+	}
+
+	settings.NextDigestAt = NextDigestTime(settings, time.Now())
+	if err := s.settingsRepo.Upsert(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// ErrInvalidSettings оборачивает ошибки проверки настроек доставки.
+var ErrInvalidSettings = errors.New("invalid delivery settings")
+
+func ValidateUserSettings(settings *models.UserSettings) error {
+	if err := validateUserSettings(settings); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSettings, err)
+	}
+	return nil
+}
+
+func validateUserSettings(settings *models.UserSettings) error {
+	switch settings.DeliveryMode {
+	case models.DeliveryModeInstant, models.DeliveryModeHourly, models.DeliveryModeDaily, models.DeliveryModeWeekly:
+	default:
+		return fmt.Errorf("unknown delivery mode: %s", settings.DeliveryMode)
+	}
+
+	switch settings.GroupBy {
+	case models.DigestGroupBySource, models.DigestGroupByCategory:
+	default:
+		return fmt.Errorf("unknown digest grouping: %s", settings.GroupBy)
+	}
+
+	if _, _, err := parseDigestTime(settings.DigestTime); err != nil {
+		return err
+	}
+	if settings.DigestWeekday < 1 || settings.DigestWeekday > 7 {
+		return fmt.Errorf("digest weekday must be between 1 (Monday) and 7 (Sunday)")
+	}
+	if _, err := time.LoadLocation(settings.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone: %s", settings.Timezone)
+	}
+
+	return nil
+}
+
+// NextDigestTime возвращает момент следующей отправки дайджеста после now
+// с учетом часового пояса пользователя. Для режима instant возвращает nil.
+func NextDigestTime(settings *models.UserSettings, now time.Time) *time.Time {
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	hour, minute, err := parseDigestTime(settings.DigestTime)
+	if err != nil {
+		hour, minute = 9, 0
+	}
+
+	var next time.Time
+	switch settings.DeliveryMode {
+	case models.DeliveryModeHourly:
+		next = time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, loc).Add(time.Hour)
+	case models.DeliveryModeDaily:
+		next = time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+		if !next.After(local) {
+			next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, loc)
+		}
+	case models.DeliveryModeWeekly:
+		weekday := time.Weekday(settings.DigestWeekday % 7)
+		days := (int(weekday) - int(local.Weekday()) + 7) % 7
+		next = time.Date(local.Year(), local.Month(), local.Day()+days, hour, minute, 0, 0, loc)
+		if !next.After(local) {
+			next = time.Date(local.Year(), local.Month(), local.Day()+days+7, hour, minute, 0, 0, loc)
+		}
+	default:
+		return nil
+	}
+
+	next = next.UTC()
+	return &next
+}
+
+func parseDigestTime(value string) (int, int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("digest time must be in HH:MM format")
+	}
+	return parsed.Hour(), parsed.Minute(), nil
+}
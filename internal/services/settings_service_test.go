@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextDigestTime_Instant(t *testing.T) {
+	settings := DefaultUserSettings(1)
+
+	next := NextDigestTime(settings, time.Now())
+
+	assert.Nil(t, next)
+}
+
+func TestNextDigestTime_Hourly(t *testing.T) {
+	settings := DefaultUserSettings(1)
+	settings.DeliveryMode = models.DeliveryModeHourly
+	now := time.Date(2025, 3, 10, 14, 25, 0, 0, time.UTC)
+
+	next := NextDigestTime(settings, now)
+
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC), *next)
+}
+
+func TestNextDigestTime_HourlyInHalfHourTimezone(t *testing.T) {
+	settings := DefaultUserSettings(1)
+	settings.DeliveryMode = models.DeliveryModeHourly
+	settings.Timezone = "Asia/Kolkata"
+	// 19:55 по Калькутте (UTC+5:30)
+	now := time.Date(2025, 3, 10, 14, 25, 0, 0, time.UTC)
+
+	next := NextDigestTime(settings, now)
+
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2025, 3, 10, 14, 30, 0, 0, time.UTC), *next)
+}
+
+func TestNextDigestTime_DailyInUserTimezone(t *testing.T) {
+	settings := DefaultUserSettings(1)
+	settings.DeliveryMode = models.DeliveryModeDaily
+	settings.DigestTime = "09:00"
+	settings.Timezone = "Europe/Moscow"
+
+	// 05:00 UTC = 08:00 MSK, дайджест сегодня в 09:00 MSK
+	next := NextDigestTime(settings, time.Date(2025, 3, 10, 5, 0, 0, 0, time.UTC))
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2025, 3, 10, 6, 0, 0, 0, time.UTC), *next)
+
+	// 07:00 UTC = 10:00 MSK, сегодняшний дайджест уже прошел
+	next = NextDigestTime(settings, time.Date(2025, 3, 10, 7, 0, 0, 0, time.UTC))
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2025, 3, 11, 6, 0, 0, 0, time.UTC), *next)
+}
+
+func TestNextDigestTime_Weekly(t *testing.T) {
+	settings := DefaultUserSettings(1)
+	settings.DeliveryMode = models.DeliveryModeWeekly
+	settings.DigestWeekday = 7
+	settings.DigestTime = "20:30"
+
+	// 2025-03-10 — понедельник
+	next := NextDigestTime(settings, time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2025, 3, 16, 20, 30, 0, 0, time.UTC), *next)
+
+	// В воскресенье после 20:30 — через неделю
+	next = NextDigestTime(settings, time.Date(2025, 3, 16, 21, 0, 0, 0, time.UTC))
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2025, 3, 23, 20, 30, 0, 0, time.UTC), *next)
+}
+
+func TestValidateUserSettings(t *testing.T) {
+	settings := DefaultUserSettings(1)
+	require.NoError(t, ValidateUserSettings(settings))
+
+	settings.DigestTime = "25:00"
+	assert.ErrorIs(t, ValidateUserSettings(settings), ErrInvalidSettings)
+
+	settings = DefaultUserSettings(1)
+	settings.Timezone = "Mars/Olympus"
+	assert.ErrorIs(t, ValidateUserSettings(settings), ErrInvalidSettings)
+
+	settings = DefaultUserSettings(1)
+	settings.DeliveryMode = "monthly"
+	assert.ErrorIs(t, ValidateUserSettings(settings), ErrInvalidSettings)
+}
+
+func TestSettingsService_UpdateSettingsStorageErrorIsNotValidation(t *testing.T) {
+	repo := new(MockSettingsRepository)
+	service := NewSettingsService(repo)
+	repo.On("GetByUserID", mock.Anything, int64(1)).Return(nil, errors.New("db down"))
+
+	_, err := service.UpdateSettings(context.Background(), 1, &models.UpdateSettingsRequest{})
+
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidSettings)
+}
+
+func TestBuildDigest_GroupByCategory(t *testing.T) {
+	older := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	deliveries := []models.PendingDelivery{
+		{DeliveryID: 1, ChatID: 42, SourceName: "Habr", CategoryName: "IT", News: models.NewsItem{Title: "a", PublishedAt: older}},
+		{DeliveryID: 2, ChatID: 42, SourceName: "Lenta", CategoryName: "", News: models.NewsItem{Title: "b", PublishedAt: older}},
+		{DeliveryID: 3, ChatID: 42, SourceName: "3DNews", CategoryName: "IT", News: models.NewsItem{Title: "c", PublishedAt: newer}},
+	}
+
+	digest := BuildDigest(deliveries, models.DigestGroupByCategory)
+
+	assert.Equal(t, int64(42), digest.ChatID)
+	require.Len(t, digest.Groups, 2)
+	assert.Equal(t, "IT", digest.Groups[0].Name)
+	require.Len(t, digest.Groups[0].News, 2)
+	assert.Equal(t, "c", digest.Groups[0].News[0].Title)
+	assert.Equal(t, "Без категории", digest.Groups[1].Name)
+}
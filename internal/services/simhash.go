@@ -0,0 +1,85 @@
+package services
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// Новости с расстоянием Хэмминга между отпечатками не больше этого
+	// значения считаются одной и той же историей.
+	DuplicateMaxDistance = 6
+	// Копии ищутся только среди новостей, опубликованных в пределах этого окна.
+	DuplicateWindow = 48 * time.Hour
+
+	titleWeight          = 3
+	minFingerprintTokens = 5
+	maxFingerprintTokens = 300
+)
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// Fingerprint вычисляет 64-битный SimHash по нормализованным заголовку и тексту.
+// Признаками служат пары соседних слов; слова заголовка имеют больший вес,
+// так как перепечатки одной новости обычно отличаются вступлением и подписью.
+// Для слишком коротких текстов отпечаток не строится: он дает ложные совпадения.
+func Fingerprint(title, content string) (uint64, bool) {
+	titleTokens := normalizeTokens(title)
+	contentTokens := normalizeTokens(htmlTagPattern.ReplaceAllString(content, " "))
+	if len(titleTokens)+len(contentTokens) < minFingerprintTokens {
+		return 0, false
+	}
+
+	var weights [64]int
+	addShingles(&weights, titleTokens, titleWeight)
+	addShingles(&weights, contentTokens, 1)
+
+	var fingerprint uint64
+	for i, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << uint(i)
+		}
+	}
+	return fingerprint, true
+}
+
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func normalizeTokens(text string) []string {
+	tokens := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(tokens) > maxFingerprintTokens {
+		tokens = tokens[:maxFingerprintTokens]
+	}
+	return tokens
+}
+
+func addShingles(weights *[64]int, tokens []string, weight int) {
+	if len(tokens) == 1 {
+		addFeature(weights, tokens[0], weight)
+		return
+	}
+	for i := 1; i < len(tokens); i++ {
+		addFeature(weights, tokens[i-1]+" "+tokens[i], weight)
+	}
+}
+
+func addFeature(weights *[64]int, feature string, weight int) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(feature))
+	sum := h.Sum64()
+	for i := range weights {
+		if sum&(1<<uint(i)) != 0 {
+			weights[i] += weight
+		} else {
+			weights[i] -= weight
+		}
+	}
+}
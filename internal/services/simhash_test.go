@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint_NearDuplicates(t *testing.T) {
+	original, ok := Fingerprint(
+		"Центробанк сохранил ключевую ставку на уровне 21%",
+		"<p>Совет директоров Банка России принял решение сохранить ключевую ставку на уровне 21% годовых. "+
+			"Регулятор отметил, что инфляционное давление остается высоким, а кредитная активность замедляется.</p>",
+	)
+	require.True(t, ok)
+
+	reprint, ok := Fingerprint(
+		"Центробанк сохранил ключевую ставку на уровне 21%",
+		"Совет директоров Банка России принял решение сохранить ключевую ставку на уровне 21% годовых. "+
+			"Регулятор отметил, что инфляционное давление остается высоким, а кредитная активность замедляется. Подробнее на сайте.",
+	)
+	require.True(t, ok)
+
+	other, ok := Fingerprint(
+		"Сборная выиграла товарищеский матч со счетом 3:1",
+		"Национальная команда обыграла соперника в товарищеском матче. Два мяча забил капитан, еще один - дебютант.",
+	)
+	require.True(t, ok)
+
+	assert.LessOrEqual(t, HammingDistance(original, reprint), DuplicateMaxDistance)
+	assert.Greater(t, HammingDistance(original, other), DuplicateMaxDistance)
+}
+
+func TestFingerprint_ShortText(t *testing.T) {
+	_, ok := Fingerprint("Новость дня", "")
+	assert.False(t, ok)
+}
@@ -3,18 +3,28 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
 	"github.com/SANEKNAYMCHIK/newsBot/internal/repositories"
 )
 
+var ErrSourceAlreadyExists = errors.New("source with this URL already exists")
+
 type SourceService struct {
 	sourceRepo repositories.SourceRepository
+	discoverer *FeedDiscoverer
+	parser     *RssParser
 }
 
-func NewSourceService(sourceRepo repositories.SourceRepository) *SourceService {
-	return &SourceService{sourceRepo: sourceRepo}
+func NewSourceService(sourceRepo repositories.SourceRepository, discoverer *FeedDiscoverer, parser *RssParser) *SourceService {
+	return &SourceService{
+		sourceRepo: sourceRepo,
+		discoverer: discoverer,
+		parser:     parser,
+	}
 }
 
 func (s *SourceService) GetActiveSources(ctx context.Context) ([]models.Source, error) {
@@ -51,19 +61,157 @@ func (s *SourceService) GetAllSources(ctx context.Context, page, pageSize int) (
 	}, nil
 }
 
-func (s *SourceService) CreateSource(ctx context.Context, req *models.CreateSourceRequest) (*models.Source, error) {
+func (s *SourceService) GetSourcesHealth(ctx context.Context, onlyFailing bool, page, pageSize int) (*models.PaginatedResponse[models.SourceHealth], error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	health, total, err := s.sourceRepo.GetHealth(ctx, onlyFailing, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / pageSize
+	if int(total)%pageSize > 0 {
+		totalPages++
+	}
+	return &models.PaginatedResponse[models.SourceHealth]{
+		Data:       health,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// CreateSource проверяет ленту пробной загрузкой и добавляет источник. Если
+// передан адрес сайта, а не ленты, лента ищется на странице; при нескольких
+// найденных лентах возвращается *FeedChoiceError, и запрос нужно повторить с
+// адресом выбранной ленты. Пустое имя заполняется заголовком ленты.
+func (s *SourceService) CreateSource(ctx context.Context, req *models.CreateSourceRequest) (*models.CreateSourceResponse, error) {
+	feedURL, canonicalURL, preview, err := s.resolveFeed(ctx, req.URL, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" && preview != nil {
+		name = TruncateTitle(preview.Title)
+	}
+	if name == "" {
+		return nil, errors.New("source name is required: the feed has no title")
+	}
+
 	source := &models.Source{
-		Name:       req.Name,
-		URL:        req.URL,
-		CategoryID: req.CategoryID,
-		IsActive:   true,
+		Name:         name,
+		URL:          feedURL,
+		CanonicalURL: canonicalURL,
+		CategoryID:   req.CategoryID,
+		IsActive:     true,
 	}
 
 	if err := s.sourceRepo.Create(ctx, source); err != nil {
 		return nil, err
 	}
 
-	return source, nil
+	return &models.CreateSourceResponse{Source: *source, Preview: preview}, nil
+}
+
+// resolveFeed находит ленту по адресу сайта или ленты, проверяет, что другого
+// источника (кроме sourceID) с ней нет, и проверяет ее пробной загрузкой.
+// Возвращает адрес ленты, его каноническую форму и превью.
+func (s *SourceService) resolveFeed(ctx context.Context, rawURL string, sourceID int64) (string, string, *models.FeedPreview, error) {
+	if err := s.checkDuplicate(ctx, rawURL, sourceID); err != nil {
+		return "", "", nil, err
+	}
+
+	feedURL := rawURL
+	var discovered *discoveredFeed
+	if s.discoverer != nil {
+		// Прочие ошибки поиска не возвращаются: причину точнее покажет проверка ленты
+		feeds, err := s.discoverer.discover(ctx, rawURL)
+		switch {
+		case errors.Is(err, ErrFeedNotFound):
+			return "", "", nil, err
+		case errors.Is(err, ErrFeedTooLarge):
+			return "", "", nil, fmt.Errorf("%w: %w", ErrInvalidFeed, err)
+		case err != nil:
+		case len(feeds) > 1:
+			candidates := make([]models.FeedCandidate, 0, len(feeds))
+			for _, feed := range feeds {
+				candidates = append(candidates, feed.FeedCandidate)
+			}
+			return "", "", nil, &FeedChoiceError{Candidates: candidates}
+		default:
+			feedURL = feeds[0].URL
+			discovered = &feeds[0]
+		}
+	}
+
+	canonicalURL, err := CanonicalizeURL(feedURL)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if feedURL != rawURL {
+		if err := s.checkDuplicate(ctx, feedURL, sourceID); err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	var preview *models.FeedPreview
+	if s.parser != nil {
+		// Лента, найденная поиском, уже загружена и повторно не скачивается
+		if discovered != nil {
+			preview, err = s.parser.previewFeed(discovered.body, discovered.contentType)
+		} else {
+			preview, err = s.parser.ValidateFeed(ctx, feedURL)
+		}
+		if err != nil {
+			return "", "", nil, err
+		}
+	}
+	return feedURL, canonicalURL, preview, nil
+}
+
+// checkDuplicate возвращает ErrSourceAlreadyExists, если адрес уже занят
+// источником, отличным от sourceID.
+func (s *SourceService) checkDuplicate(ctx context.Context, rawURL string, sourceID int64) error {
+	existing, err := s.FindByURL(ctx, rawURL)
+	if errors.Is(err, ErrSourceAlreadyExists) && existing.ID == sourceID {
+		return nil
+	}
+	return err
+}
+
+// FindByURL проверяет, нет ли уже источника с таким адресом. Возвращает
+// ErrSourceAlreadyExists вместе с найденным источником.
+func (s *SourceService) FindByURL(ctx context.Context, rawURL string) (*models.Source, error) {
+	canonicalURL, err := CanonicalizeURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.sourceRepo.GetByURL(ctx, canonicalURL)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, ErrSourceAlreadyExists
+	}
+	return nil, nil
+}
+
+// DiscoverFeeds ищет ленты по адресу сайта или ленты.
+func (s *SourceService) DiscoverFeeds(ctx context.Context, rawURL string) ([]models.FeedCandidate, error) {
+	if _, err := CanonicalizeURL(rawURL); err != nil {
+		return nil, err
+	}
+	if s.discoverer == nil {
+		return []models.FeedCandidate{{URL: rawURL}}, nil
+	}
+	return s.discoverer.Discover(ctx, rawURL)
 }
 
 func (s *SourceService) UpdateSource(ctx context.Context, sourceID int, req *models.UpdateSourceRequest) (*models.Source, error) {
@@ -78,8 +226,14 @@ func (s *SourceService) UpdateSource(ctx context.Context, sourceID int, req *mod
 	if req.Name != nil {
 		source.Name = *req.Name
 	}
-	if req.URL != nil {
-		source.URL = *req.URL
+	if req.URL != nil && *req.URL != source.URL {
+		// Новый адрес проверяется так же, как при добавлении источника
+		feedURL, canonicalURL, _, err := s.resolveFeed(ctx, *req.URL, source.ID)
+		if err != nil {
+			return nil, err
+		}
+		source.URL = feedURL
+		source.CanonicalURL = canonicalURL
 	}
 	if req.CategoryID != nil {
 		source.CategoryID = req.CategoryID
@@ -87,6 +241,34 @@ func (s *SourceService) UpdateSource(ctx context.Context, sourceID int, req *mod
 	if req.IsActive != nil {
 		source.IsActive = *req.IsActive
 	}
+	if req.MinFetchInterval != nil {
+		source.MinFetchInterval = *req.MinFetchInterval
+	}
+	if req.MaxFetchInterval != nil {
+		source.MaxFetchInterval = *req.MaxFetchInterval
+	}
+	if req.FetchFullText != nil {
+		source.FetchFullText = *req.FetchFullText
+	}
+	if req.Encoding != nil {
+		source.Encoding = nil
+		if strings.TrimSpace(*req.Encoding) != "" {
+			encoding, err := NormalizeEncoding(*req.Encoding)
+			if err != nil {
+				return nil, err
+			}
+			source.Encoding = &encoding
+		}
+	}
+	if req.RetentionDays != nil {
+		source.RetentionDays = nil
+		if *req.RetentionDays >= 0 {
+			source.RetentionDays = req.RetentionDays
+		}
+	}
+	if source.MinFetchInterval > source.MaxFetchInterval {
+		return nil, errors.New("min fetch interval must not exceed max fetch interval")
+	}
 
 	if err := s.sourceRepo.Update(ctx, source); err != nil {
 		return nil, err
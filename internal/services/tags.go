@@ -0,0 +1,59 @@
+package services
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	maxTagLength   = 100
+	maxTagsPerItem = 20
+)
+
+// TagSlug нормализует имя рубрики или автора для поиска: нижний регистр и
+// одиночные пробелы. Пустая строка означает, что имя не подходит для тега.
+func TagSlug(name string) string {
+	slug := strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if utf8.RuneCountInString(slug) > maxTagLength {
+		return ""
+	}
+	return slug
+}
+
+// ExtractTags собирает рубрики и авторов элемента ленты. Повторы с одинаковым
+// slug объединяются, сохраняется первое написание имени.
+func ExtractTags(item *gofeed.Item) []models.Tag {
+	var tags []models.Tag
+	seen := make(map[string]bool)
+	add := func(name, kind string) {
+		name = strings.Join(strings.Fields(name), " ")
+		slug := TagSlug(name)
+		if slug == "" || seen[kind+":"+slug] || len(tags) == maxTagsPerItem {
+			return
+		}
+		seen[kind+":"+slug] = true
+		tags = append(tags, models.Tag{Name: name, Slug: slug, Kind: kind})
+	}
+
+	authors := item.Authors
+	if len(authors) == 0 && item.Author != nil {
+		authors = []*gofeed.Person{item.Author}
+	}
+	for _, author := range authors {
+		if author == nil {
+			continue
+		}
+		name := author.Name
+		if name == "" {
+			name = author.Email
+		}
+		add(name, models.TagKindAuthor)
+	}
+	for _, category := range item.Categories {
+		add(category, models.TagKindCategory)
+	}
+	return tags
+}
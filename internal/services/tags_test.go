@@ -0,0 +1,46 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const taggedFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel>
+<title>Test</title>
+<item>
+  <title>Column</title>
+  <link>https://example.com/column/1</link>
+  <dc:creator>Иван  Петров</dc:creator>
+  <category>Экономика</category>
+  <category> экономика </category>
+  <category>Рынки и   биржи</category>
+  <category>   </category>
+</item>
+</channel>
+</rss>`
+
+func TestExtractTags(t *testing.T) {
+	feed, err := gofeed.NewParser().ParseString(taggedFeed)
+	require.NoError(t, err)
+	require.Len(t, feed.Items, 1)
+
+	tags := ExtractTags(feed.Items[0])
+	assert.Equal(t, []models.Tag{
+		{Name: "Иван Петров", Slug: "иван петров", Kind: models.TagKindAuthor},
+		{Name: "Экономика", Slug: "экономика", Kind: models.TagKindCategory},
+		{Name: "Рынки и биржи", Slug: "рынки и биржи", Kind: models.TagKindCategory},
+	}, tags)
+}
+
+func TestTagSlug(t *testing.T) {
+	assert.Equal(t, "иван петров", TagSlug("  Иван\tПЕТРОВ "))
+	assert.Equal(t, "", TagSlug("   "))
+	assert.Equal(t, "", TagSlug(strings.Repeat("а", maxTagLength+1)))
+}
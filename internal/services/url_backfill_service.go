@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/SANEKNAYMCHIK/newsBot/internal/repositories"
+)
+
+// Имя меняется вместе с правилами CanonicalizeURL, чтобы пересчет выполнился
+// еще раз: v2 перестала сортировать значения повторяющихся параметров.
+const canonicalURLsBackfill = "canonical_urls_v2"
+
+// URLBackfillService один раз пересчитывает canonical_url источников и
+// новостей, сохраненных до появления CanonicalizeURL: миграция скопировала в
+// это поле ссылки как есть, и такие записи не находились по канонической
+// ссылке. Если каноническая ссылка уже занята другой записью, запись
+// остается без изменений.
+type URLBackfillService struct {
+	backfillRepo repositories.BackfillRepository
+	batchSize    int
+}
+
+func NewURLBackfillService(backfillRepo repositories.BackfillRepository, batchSize int) *URLBackfillService {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	return &URLBackfillService{
+		backfillRepo: backfillRepo,
+		batchSize:    batchSize,
+	}
+}
+
+func (s *URLBackfillService) Run(ctx context.Context) error {
+	done, err := s.backfillRepo.IsDone(ctx, canonicalURLsBackfill)
+	if err != nil || done {
+		return err
+	}
+
+	sources, err := s.backfillRepo.GetSourceURLs(ctx)
+	if err != nil {
+		return err
+	}
+	updatedSources, err := s.backfillRepo.UpdateSourceCanonicalURLs(ctx, changedCanonicalURLs(sources, true))
+	if err != nil {
+		return err
+	}
+
+	var updatedNews int64
+	var afterID int64
+	for {
+		news, err := s.backfillRepo.GetNewsURLs(ctx, afterID, s.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(news) == 0 {
+			break
+		}
+		updated, err := s.backfillRepo.UpdateNewsCanonicalURLs(ctx, changedCanonicalURLs(news, false))
+		if err != nil {
+			return err
+		}
+		updatedNews += updated
+		afterID = news[len(news)-1].ID
+	}
+
+	log.Printf("Canonical URLs backfilled: %d sources, %d news items", updatedSources, updatedNews)
+	return s.backfillRepo.MarkDone(ctx, canonicalURLsBackfill)
+}
+
+// changedCanonicalURLs возвращает записи, у которых каноническая ссылка
+// отличается от сохраненной. Из записей с одинаковой новой ссылкой остается
+// первая. Источники с неразбираемой ссылкой пропускаются, у новостей такая
+// ссылка сохраняется как есть, как и при разборе лент.
+func changedCanonicalURLs(urls []models.StoredURL, strict bool) []models.StoredURL {
+	seen := make(map[string]bool, len(urls))
+	var changed []models.StoredURL
+	for _, u := range urls {
+		canonical := canonicalOrRaw(u.URL)
+		if strict {
+			var err error
+			if canonical, err = CanonicalizeURL(u.URL); err != nil {
+				continue
+			}
+		}
+		if canonical == u.CanonicalURL || seen[canonical] {
+			continue
+		}
+		seen[canonical] = true
+		changed = append(changed, models.StoredURL{ID: u.ID, URL: u.URL, CanonicalURL: canonical})
+	}
+	return changed
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SANEKNAYMCHIK/newsBot/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockBackfillRepository struct {
+	mock.Mock
+}
+
+func (m *MockBackfillRepository) IsDone(ctx context.Context, name string) (bool, error) {
+	args := m.Called(ctx, name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockBackfillRepository) MarkDone(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
+
+func (m *MockBackfillRepository) GetSourceURLs(ctx context.Context) ([]models.StoredURL, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.StoredURL), args.Error(1)
+}
+
+func (m *MockBackfillRepository) GetNewsURLs(ctx context.Context, afterID int64, limit int) ([]models.StoredURL, error) {
+	args := m.Called(ctx, afterID, limit)
+	return args.Get(0).([]models.StoredURL), args.Error(1)
+}
+
+func (m *MockBackfillRepository) UpdateSourceCanonicalURLs(ctx context.Context, urls []models.StoredURL) (int64, error) {
+	args := m.Called(ctx, urls)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockBackfillRepository) UpdateNewsCanonicalURLs(ctx context.Context, urls []models.StoredURL) (int64, error) {
+	args := m.Called(ctx, urls)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestURLBackfillService_RunCanonicalizesStoredURLs(t *testing.T) {
+	repo := new(MockBackfillRepository)
+	service := NewURLBackfillService(repo, 2)
+
+	repo.On("IsDone", mock.Anything, canonicalURLsBackfill).Return(false, nil)
+	repo.On("GetSourceURLs", mock.Anything).Return([]models.StoredURL{
+		{ID: 1, URL: "HTTP://WWW.Example.com/feed/", CanonicalURL: "HTTP://WWW.Example.com/feed/"},
+		{ID: 2, URL: "https://example.com/feed?utm_source=x", CanonicalURL: "https://example.com/feed?utm_source=x"},
+		{ID: 3, URL: "https://other.com/rss", CanonicalURL: "https://other.com/rss"},
+	}, nil)
+	repo.On("UpdateSourceCanonicalURLs", mock.Anything, []models.StoredURL{
+		{ID: 1, URL: "HTTP://WWW.Example.com/feed/", CanonicalURL: "https://example.com/feed"},
+	}).Return(int64(1), nil)
+	repo.On("GetNewsURLs", mock.Anything, int64(0), 2).Return([]models.StoredURL{
+		{ID: 10, URL: "https://example.com/a/", CanonicalURL: "https://example.com/a/"},
+		{ID: 11, URL: "not a url", CanonicalURL: "not a url"},
+	}, nil)
+	repo.On("UpdateNewsCanonicalURLs", mock.Anything, []models.StoredURL{
+		{ID: 10, URL: "https://example.com/a/", CanonicalURL: "https://example.com/a"},
+	}).Return(int64(1), nil)
+	repo.On("GetNewsURLs", mock.Anything, int64(11), 2).Return([]models.StoredURL{}, nil)
+	repo.On("MarkDone", mock.Anything, canonicalURLsBackfill).Return(nil)
+
+	require.NoError(t, service.Run(context.Background()))
+	repo.AssertExpectations(t)
+}
+
+func TestURLBackfillService_RunSkipsWhenDone(t *testing.T) {
+	repo := new(MockBackfillRepository)
+	service := NewURLBackfillService(repo, 2)
+
+	repo.On("IsDone", mock.Anything, canonicalURLsBackfill).Return(true, nil)
+
+	assert.NoError(t, service.Run(context.Background()))
+	repo.AssertNotCalled(t, "GetSourceURLs", mock.Anything)
+}
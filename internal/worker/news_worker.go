@@ -3,6 +3,8 @@ package worker
 import (
 	"context"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/SANEKNAYMCHIK/newsBot/internal/services"
@@ -11,33 +13,45 @@ import (
 type NewsWorker struct {
 	rssService *services.RssService
 	interval   time.Duration
-	isRunning  bool
-	cancel     context.CancelFunc
+
+	// mu защищает cancel и не дает запустить воркер, пока предыдущий запуск
+	// не остановлен полностью
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// taskRunning не дает запустить новый сбор, пока не закончился предыдущий
+	taskRunning atomic.Bool
 }
 
 func NewNewsWorker(rssService *services.RssService, interval time.Duration) *NewsWorker {
 	return &NewsWorker{
 		rssService: rssService,
 		interval:   interval,
-		isRunning:  false,
 	}
 }
 
 func (w *NewsWorker) Start(ctx context.Context) {
-	if w.isRunning {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel != nil {
 		log.Println("NewsWorker is already running")
 		return
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
 	w.cancel = cancel
-	w.isRunning = true
 
 	log.Printf("Starting NewsWorker with interval %v", w.interval)
 
-	go w.runTask(ctx)
+	w.wg.Add(2)
+	go func() {
+		defer w.wg.Done()
+		w.runTask(ctx)
+	}()
 
 	go func() {
+		defer w.wg.Done()
 		ticker := time.NewTicker(w.interval)
 		defer ticker.Stop()
 
@@ -45,23 +59,38 @@ func (w *NewsWorker) Start(ctx context.Context) {
 			select {
 			case <-ctx.Done():
 				log.Println("NewsWorker stopping...")
-				w.isRunning = false
 				return
 			case <-ticker.C:
-				go w.runTask(ctx)
+				w.wg.Add(1)
+				go func() {
+					defer w.wg.Done()
+					w.runTask(ctx)
+				}()
 			}
 		}
 	}()
 }
 
+// Stop останавливает воркер и ждет завершения текущего сбора.
 func (w *NewsWorker) Stop() {
-	if w.cancel != nil {
-		w.cancel()
-		w.isRunning = false
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel == nil {
+		return
 	}
+	w.cancel()
+	w.wg.Wait()
+	w.cancel = nil
 }
 
 func (w *NewsWorker) runTask(ctx context.Context) {
+	if !w.taskRunning.CompareAndSwap(false, true) {
+		log.Println("Previous news fetch task is still running, skipping")
+		return
+	}
+	defer w.taskRunning.Store(false)
+
 	log.Println("Starting news fetch task...")
 	startTime := time.Now()
 